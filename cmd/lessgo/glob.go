@@ -0,0 +1,68 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// globFiles returns the files matching pattern. Besides the filepath.Match
+// syntax, a "**" path segment matches zero or more directories.
+func globFiles(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	// Validate the pattern up front, like filepath.Glob does
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+
+	parts := splitPath(filepath.Clean(pattern))
+	var matches []string
+	err := filepath.WalkDir(globBase(pattern), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if matchSegments(parts, splitPath(filepath.Clean(path))) {
+			matches = append(matches, path)
+		}
+		return nil
+	})
+	return matches, err
+}
+
+// splitPath splits a cleaned path into its segments
+func splitPath(path string) []string {
+	if path == "." {
+		return nil
+	}
+	return strings.Split(filepath.ToSlash(path), "/")
+}
+
+// matchSegments matches path segments against pattern segments, where a "**"
+// segment consumes any number of path segments
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(pattern[0], path[0]); !ok {
+			return false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	return len(path) == 0
+}
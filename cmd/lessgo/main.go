@@ -1,12 +1,17 @@
 package main
 
 import (
+	"bytes"
 	"flag"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
 
+	"github.com/titpetric/lessgo"
 	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/expression/functions"
+	"github.com/titpetric/lessgo/internal/strings"
 	"github.com/titpetric/lessgo/renderer"
 )
 
@@ -25,6 +30,8 @@ func main() {
 		fmtCmd(os.Args[2:])
 	case "generate":
 		generateCmd(os.Args[2:])
+	case "version", "--version", "-v":
+		versionCmd()
 	case "help":
 		printUsage()
 	default:
@@ -80,6 +87,9 @@ func fmtCmd(args []string) {
 	}
 
 	write := fs.Bool("w", false, "write formatted output back to file")
+	check := fs.Bool("check", false, "exit non-zero and print the file name if it isn't formatted")
+	diff := fs.Bool("diff", false, "print a unified diff of the formatting changes")
+	lineWidth := fs.Int("line-width", 0, "wrap comma-separated values of declarations longer than this, 0 disables")
 	fs.Parse(args)
 
 	if fs.NArg() < 1 {
@@ -89,13 +99,12 @@ func fmtCmd(args []string) {
 
 	filePath := fs.Arg(0)
 
-	// Parse the .less file
-	file, err := os.Open(filePath)
+	// Read the .less file
+	original, err := os.ReadFile(filePath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error opening file: %v\n", err)
 		os.Exit(1)
 	}
-	defer file.Close()
 
 	// Get the directory of the file for resolving imports
 	dir := filepath.Dir(filePath)
@@ -104,7 +113,7 @@ func fmtCmd(args []string) {
 	}
 	fileSystem := os.DirFS(dir)
 
-	parser := dst.NewParserWithFS(file, fileSystem)
+	parser := dst.NewParserWithFS(bytes.NewReader(original), fileSystem)
 	astFile, err := parser.Parse()
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error parsing file: %v\n", err)
@@ -113,8 +122,23 @@ func fmtCmd(args []string) {
 
 	// Format the AST
 	formatter := dst.NewFormatter()
+	formatter.LineWidth = *lineWidth
 	formatted := formatter.Format(astFile)
 
+	if *check || *diff {
+		// Dry run: compare in memory, never write
+		changed := formatted != string(original)
+		if *diff {
+			fmt.Print(unifiedDiff(filePath, string(original), formatted))
+		} else if changed {
+			fmt.Println(filePath)
+		}
+		if changed {
+			os.Exit(1)
+		}
+		return
+	}
+
 	if *write {
 		// Write back to file
 		err := os.WriteFile(filePath, []byte(formatted), 0644)
@@ -137,17 +161,66 @@ func generateCmd(args []string) {
 	}
 
 	output := fs.String("o", "", "output file (default: stdout)")
+	outDir := fs.String("out-dir", "", "write one .css file per input into this directory")
+	clean := fs.Bool("clean", false, "with -out-dir, remove previously generated .css files that have no input")
+	postProcess := fs.String("postprocess", "", "shell command to pipe rendered CSS through (stdin to stdout)")
+	modernColors := fs.Bool("modern-colors", false, "emit computed colors as rgb(r g b / a%) instead of rgba(r, g, b, a)")
+	precision := fs.Int("precision", functions.DefaultPrecision, "number of decimal places for numeric results")
+	outputStyle := fs.String("output-style", "expanded", "output style: expanded, compact or compressed")
+	warnRedefine := fs.Bool("warn-redefine", false, "warn about variables defined more than once in the same scope")
+	dedupeMedia := fs.Bool("dedupe-media", false, "merge adjacent @media blocks with the same condition and drop repeated rules in them")
+	stripZeroUnits := fs.Bool("strip-zero-units", false, "write zero lengths and percentages as 0, e.g. 0px becomes 0")
+	dataURILimit := fs.Int("data-uri-limit", functions.DefaultDataURISizeLimit, "largest file in bytes data-uri() inlines, larger files use url(), 0 disables the limit")
+	noIECompat := fs.Bool("no-ie-compat", false, "inline files of any size with data-uri()")
+	omitFinalNewline := fs.Bool("omit-final-newline", false, "don't end the output with a newline")
+	includeImportComments := fs.Bool("include-comments-from-imports", false, "keep all comments from imported files, not only /*! ... */ comments")
+	sourceMapInline := fs.Bool("source-map-inline", false, "append a source map to the output as a base64 sourceMappingURL comment")
+	vars := varFlags{}
+	fs.Var(vars, "var", "override a global variable as name=value (repeatable)")
 	fs.Parse(args)
 
+	options := renderer.Options{
+		ModernColors:          *modernColors,
+		Precision:             *precision,
+		StripZeroUnits:        *stripZeroUnits,
+		DedupeMedia:           *dedupeMedia,
+		OmitFinalNewline:      *omitFinalNewline,
+		IncludeImportComments: *includeImportComments,
+		SourceMapInline:       *sourceMapInline,
+	}
+	options.DataURISizeLimit = *dataURILimit
+	if *noIECompat || *dataURILimit <= 0 {
+		options.DataURISizeLimit = -1
+	}
+	style, err := renderer.ParseOutputStyle(*outputStyle)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	options.OutputStyle = style
+	if *postProcess != "" {
+		options.PostProcess = commandPostProcessor(*postProcess)
+	}
+	if *warnRedefine {
+		options.WarnRedefine = func(name string) {
+			fmt.Fprintf(os.Stderr, "warning: variable @%s is redefined\n", name)
+		}
+	}
+
 	if fs.NArg() < 1 {
 		fs.Usage()
 		os.Exit(1)
 	}
 
+	if *output != "" && *outDir != "" {
+		fmt.Fprintf(os.Stderr, "-o and -out-dir are mutually exclusive\n")
+		os.Exit(1)
+	}
+
 	pattern := fs.Arg(0)
 
 	// Find .less files matching pattern
-	matches, err := filepath.Glob(pattern)
+	matches, err := globFiles(pattern)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "error matching pattern: %v\n", err)
 		os.Exit(1)
@@ -160,39 +233,48 @@ func generateCmd(args []string) {
 
 	// Generate CSS output for all matched files
 	var allCSS string
+	var generated []string
+	failed := false
 
 	for _, filePath := range matches {
-		file, err := os.Open(filePath)
+		// Imports resolve against the directory of the file
+		dir := filepath.Dir(filePath)
+		css, err := lessgo.Compile(os.DirFS(dir), filepath.Base(filePath), vars, options)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "error opening %s: %v\n", filePath, err)
+			fmt.Fprintf(os.Stderr, "error compiling %s: %v\n", filePath, err)
+			failed = true
 			continue
 		}
 
-		// Get the directory of the file for resolving imports
-		dir := filepath.Dir(filePath)
-		if dir == "" {
-			dir = "."
-		}
-		fileSystem := os.DirFS(dir)
-
-		parser := dst.NewParserWithFS(file, fileSystem)
-		astFile, err := parser.Parse()
-		file.Close()
-
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error parsing %s: %v\n", filePath, err)
+		if *outDir != "" {
+			// Write one output file per input
+			outPath := outputPath(*outDir, globBase(pattern), filePath)
+			if err := writeOutput(outPath, css); err != nil {
+				fmt.Fprintf(os.Stderr, "error writing %s: %v\n", outPath, err)
+				failed = true
+				continue
+			}
+			generated = append(generated, outPath)
+			fmt.Printf("generated: %s\n", outPath)
 			continue
 		}
 
-		// Render to CSS
-		cssRenderer := renderer.NewRenderer()
-		css, err := cssRenderer.Render(astFile)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "error rendering %s: %v\n", filePath, err)
-			continue
+		// Outputs of several files are separated by a blank line
+		if allCSS != "" {
+			allCSS += "\n"
 		}
+		allCSS += css
+	}
 
-		allCSS += fmt.Sprintf("%s\n", css)
+	if *outDir != "" {
+		if failed {
+			os.Exit(1)
+		}
+		if err := updateManifest(*outDir, generated, *clean); err != nil {
+			fmt.Fprintf(os.Stderr, "error cleaning %s: %v\n", *outDir, err)
+			os.Exit(1)
+		}
+		return
 	}
 
 	if *output != "" {
@@ -209,12 +291,53 @@ func generateCmd(args []string) {
 	}
 }
 
+// varFlags collects repeated -var name=value flags
+type varFlags map[string]string
+
+// String implements flag.Value
+func (v varFlags) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value
+func (v varFlags) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if !ok || name == "" {
+		return fmt.Errorf("invalid variable %q, expected name=value", s)
+	}
+	v[name] = strings.TrimSpace(value)
+	return nil
+}
+
+// commandPostProcessor returns a post-processing hook that pipes CSS through a shell command
+func commandPostProcessor(command string) func(string) (string, error) {
+	return func(css string) (string, error) {
+		var stdout, stderr bytes.Buffer
+
+		cmd := exec.Command("sh", "-c", command)
+		cmd.Stdin = bytes.NewBufferString(css)
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+
+		if err := cmd.Run(); err != nil {
+			return "", fmt.Errorf("postprocess %q: %w: %s", command, err, stderr.String())
+		}
+		return stdout.String(), nil
+	}
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `usage: lessgo <command> [options]
 
 commands:
   fmt       - Format .less files for consistent indentation
   generate  - Generate CSS files from glob pattern of .less files
+  version   - Print the lessgo and Go version
   help      - Show this help message
 
 examples:
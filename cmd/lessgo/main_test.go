@@ -1 +1,115 @@
 package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+func TestVarFlags(t *testing.T) {
+	vars := varFlags{}
+	require.NoError(t, vars.Set("primary=#0af"))
+	require.NoError(t, vars.Set("@radius = 4px"))
+	require.Error(t, vars.Set("invalid"))
+	require.Equal(t, varFlags{"primary": "#0af", "radius": "4px"}, vars)
+}
+
+func TestOutputPath(t *testing.T) {
+	require.Equal(t, "styles", globBase("styles/*.less"))
+	require.Equal(t, ".", globBase("*.less"))
+	require.Equal(t, filepath.Join("dist", "nav", "menu.css"), outputPath("dist", "styles", filepath.Join("styles", "nav", "menu.less")))
+}
+
+func TestUpdateManifestClean(t *testing.T) {
+	outDir := t.TempDir()
+	stale := filepath.Join(outDir, "stale.css")
+	kept := filepath.Join(outDir, "kept.css")
+	handWritten := filepath.Join(outDir, "hand.css")
+
+	for _, path := range []string{stale, kept, handWritten} {
+		require.NoError(t, writeOutput(path, ".a {}\n"))
+	}
+
+	// First build generated stale.css and kept.css
+	require.NoError(t, updateManifest(outDir, []string{stale, kept}, false))
+
+	// Second build only generates kept.css
+	require.NoError(t, updateManifest(outDir, []string{kept}, true))
+
+	require.NoFileExists(t, stale)
+	require.FileExists(t, kept)
+	require.FileExists(t, handWritten)
+}
+
+func TestUpdateManifestMerge(t *testing.T) {
+	outDir := t.TempDir()
+	first := filepath.Join(outDir, "first.css")
+	second := filepath.Join(outDir, "nav", "second.css")
+
+	for _, path := range []string{first, second} {
+		require.NoError(t, writeOutput(path, ".a {}\n"))
+	}
+
+	// Two builds without -clean generate different files, both stay listed
+	require.NoError(t, updateManifest(outDir, []string{first}, false))
+	require.NoError(t, updateManifest(outDir, []string{second}, false))
+
+	manifest, err := readManifest(filepath.Join(outDir, manifestName))
+	require.NoError(t, err)
+	require.Equal(t, []string{"first.css", "nav/second.css"}, manifest)
+
+	// A -clean build removes what the first build generated
+	require.NoError(t, updateManifest(outDir, []string{second}, true))
+
+	require.NoFileExists(t, first)
+	require.FileExists(t, second)
+
+	manifest, err = readManifest(filepath.Join(outDir, manifestName))
+	require.NoError(t, err)
+	require.Equal(t, []string{"nav/second.css"}, manifest)
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	require.Equal(t, "", unifiedDiff("a.less", "a\nb\n", "a\nb\n"))
+
+	original := "1\n2\n3\n4\n5\nold\n6\n7\n8\n9\n10\n"
+	formatted := "1\n2\n3\n4\n5\nnew\n6\n7\n8\n9\n10\n"
+	want := "--- a.less\n+++ a.less\n@@ -3,7 +3,7 @@\n 3\n 4\n 5\n-old\n+new\n 6\n 7\n 8\n"
+	require.Equal(t, want, unifiedDiff("a.less", original, formatted))
+}
+
+func TestGlobFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := []string{"a.less", "b.css", "nav/menu.less", "nav/deep/item.less"}
+	for _, file := range files {
+		path := filepath.Join(dir, file)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, nil, 0644))
+	}
+
+	matches, err := globFiles(filepath.Join(dir, "*.less"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "a.less")}, matches)
+
+	matches, err = globFiles(filepath.Join(dir, "**", "*.less"))
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		filepath.Join(dir, "a.less"),
+		filepath.Join(dir, "nav", "deep", "item.less"),
+		filepath.Join(dir, "nav", "menu.less"),
+	}, matches)
+
+	matches, err = globFiles(filepath.Join(dir, "nav", "**", "item.less"))
+	require.NoError(t, err)
+	require.Equal(t, []string{filepath.Join(dir, "nav", "deep", "item.less")}, matches)
+}
+
+func TestVersionString(t *testing.T) {
+	version := versionString()
+	require.True(t, strings.HasPrefix(version, "lessgo "), version)
+	require.True(t, strings.HasSuffix(version, " "+runtime.Version()), version)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// manifestName is the file in -out-dir listing outputs from the last build
+const manifestName = ".lessgo-generated"
+
+// globBase returns the directory prefix of a glob pattern before any wildcard
+func globBase(pattern string) string {
+	dir := filepath.Dir(pattern)
+	for dir != "." && dir != string(filepath.Separator) && strings.ContainsAny(dir, "*?[") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
+
+// outputPath maps an input .less file to its .css path under outDir
+func outputPath(outDir, base, filePath string) string {
+	rel, err := filepath.Rel(base, filePath)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(filePath)
+	}
+	return filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel))+".css")
+}
+
+// writeOutput writes css to path, creating parent directories
+func writeOutput(path, css string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(css), 0644)
+}
+
+// updateManifest records the generated files in outDir. Files from earlier
+// builds stay listed until a build with clean set removes the ones that were
+// not generated this time. Only files lessgo wrote itself are ever deleted,
+// hand-written CSS is kept.
+func updateManifest(outDir string, generated []string, clean bool) error {
+	manifestPath := filepath.Join(outDir, manifestName)
+
+	current := make(map[string]bool, len(generated))
+	for _, path := range generated {
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		current[filepath.ToSlash(rel)] = true
+	}
+
+	previous, err := readManifest(manifestPath)
+	if err != nil {
+		return err
+	}
+
+	lines := make([]string, 0, len(current))
+	for rel := range current {
+		lines = append(lines, rel)
+	}
+	for _, rel := range previous {
+		path := filepath.Join(outDir, filepath.FromSlash(rel))
+		if current[rel] || filepath.Ext(rel) != ".css" || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if !clean {
+			// Keep tracking outputs of earlier builds that are still there
+			if _, err := os.Stat(path); err == nil {
+				current[rel] = true
+				lines = append(lines, rel)
+			}
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	sort.Strings(lines)
+	return os.WriteFile(manifestPath, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
+
+// readManifest reads the list of previously generated files, if any
+func readManifest(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, scanner.Err()
+}
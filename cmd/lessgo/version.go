@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// versionString returns the module version and the Go version of the build.
+// Builds from a source checkout report the VCS revision when it's known.
+func versionString() string {
+	version := "(devel)"
+	info, ok := debug.ReadBuildInfo()
+	if ok && info.Main.Version != "" {
+		version = info.Main.Version
+	}
+	if ok && version == "(devel)" {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+				version += " " + setting.Value[:12]
+			}
+		}
+	}
+	return fmt.Sprintf("lessgo %s %s", version, runtime.Version())
+}
+
+func versionCmd() {
+	fmt.Println(versionString())
+}
@@ -0,0 +1,44 @@
+package lessgo
+
+import (
+	"io/fs"
+	"path"
+
+	"github.com/titpetric/lessgo/renderer"
+)
+
+// Compile parses and renders the LESS file name from fileSystem, which can be
+// any fs.FS such as an embed.FS or fstest.MapFS. Imports are resolved relative
+// to the directory of name, as are files read by data-uri() and the image
+// functions unless options.FS is set. The vars override global variables
+// like in renderer.RenderWithVars, and a source map names the file by its
+// base name unless options.SourceMapFile is set.
+func Compile(fileSystem fs.FS, name string, vars map[string]string, options renderer.Options) (string, error) {
+	file, err := fileSystem.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	dir := path.Dir(name)
+	if dir != "." {
+		fileSystem, err = fs.Sub(fileSystem, dir)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	astFile, err := Parse(file, fileSystem)
+	if err != nil {
+		return "", err
+	}
+
+	if options.FS == nil {
+		options.FS = fileSystem
+	}
+	if options.SourceMapFile == "" {
+		options.SourceMapFile = path.Base(name)
+	}
+
+	return renderer.NewRendererWithOptions(options).RenderWithVars(astFile, "", vars)
+}
@@ -0,0 +1,74 @@
+package lessgo
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/renderer"
+)
+
+func TestCompile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"theme/colors.less": {Data: []byte("@primary: #336699;\n")},
+		"theme/main.less":   {Data: []byte("@import \"colors\";\n.button {\n  color: @primary;\n  padding: @pad;\n}\n")},
+	}
+
+	css, err := Compile(fsys, "theme/main.less", map[string]string{"pad": "4px"}, renderer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, ".button {\n  color: #336699;\n  padding: 4px;\n}\n", css)
+
+	_, err = Compile(fsys, "missing.less", nil, renderer.Options{})
+	require.Error(t, err)
+}
+
+func TestCompileImportMedia(t *testing.T) {
+	fsys := fstest.MapFS{
+		"print.less": {Data: []byte(".nav {\n  display: none;\n  @media (orientation: landscape) {\n    margin: 0;\n  }\n}\n.footer {\n  color: #000;\n}\n")},
+		"main.less":  {Data: []byte("@import \"print\" print;\n.page {\n  color: red;\n}\n")},
+	}
+
+	css, err := Compile(fsys, "main.less", nil, renderer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, "@media print {\n  .nav {\n    display: none;\n  }\n  .footer {\n    color: #000;\n  }\n}\n"+
+		"@media print and (orientation: landscape) {\n  .nav {\n    margin: 0;\n  }\n}\n"+
+		".page {\n  color: red;\n}\n", css)
+}
+
+func TestCompileScopedImport(t *testing.T) {
+	fsys := fstest.MapFS{
+		"partial.less": {Data: []byte("color: red;\n.title {\n  margin: 0;\n}\n&:hover {\n  color: blue;\n}\n")},
+		"main.less":    {Data: []byte(".card {\n  @import \"partial\";\n}\n.mixin() {\n  @import \"partial\";\n}\n.box {\n  .mixin();\n}\n")},
+	}
+
+	css, err := Compile(fsys, "main.less", nil, renderer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, ".card {\n  color: red;\n}\n.card .title {\n  margin: 0;\n}\n.card:hover {\n  color: blue;\n}\n"+
+		".box {\n  color: red;\n}\n.box .title {\n  margin: 0;\n}\n.box:hover {\n  color: blue;\n}\n", css)
+}
+
+func TestCompileExtendImported(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.less": {Data: []byte(".button {\n  padding: 4px;\n}\n.nav {\n  .item {\n    color: gray;\n  }\n}\n")},
+		"main.less": {Data: []byte("@import \"base\";\n.cta:extend(.button) {\n  color: red;\n}\n.link {\n  &:extend(.nav .item);\n}\n")},
+	}
+
+	css, err := Compile(fsys, "main.less", nil, renderer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, ".button,\n.cta {\n  padding: 4px;\n}\n.nav .item,\n.link {\n  color: gray;\n}\n.cta {\n  color: red;\n}\n", css)
+}
+
+func TestCompileDataURI(t *testing.T) {
+	fsys := fstest.MapFS{
+		"theme/img/dot.png": {Data: []byte("abc")},
+		"theme/main.less":   {Data: []byte(".a {\n  background: data-uri(\"img/dot.png\") no-repeat;\n}\n")},
+		"theme/broken.less": {Data: []byte(".a {\n  background: data-uri(\"img/missing.png\");\n}\n")},
+	}
+
+	css, err := Compile(fsys, "theme/main.less", nil, renderer.Options{})
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  background: url(\"data:image/png;base64,YWJj\") no-repeat;\n}\n", css)
+
+	_, err = Compile(fsys, "theme/broken.less", nil, renderer.Options{})
+	require.ErrorContains(t, err, "data-uri: cannot read file img/missing.png")
+}
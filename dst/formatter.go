@@ -8,6 +8,10 @@ import (
 
 // Formatter formats a DST back into .less source with consistent indentation
 type Formatter struct {
+	// LineWidth wraps comma-separated values of declarations longer than
+	// this many characters, one list item per line. Zero disables wrapping.
+	LineWidth int
+
 	indent int
 	buf    *bytes.Buffer
 }
@@ -45,9 +49,21 @@ func (f *Formatter) formatNode(node Node) {
 		f.formatMixinCall(n)
 	case *Each:
 		f.formatEach(n)
+	case *AtRule:
+		f.formatAtRule(n)
 	}
 }
 
+// formatAtRule formats a statement at-rule like @namespace
+func (f *Formatter) formatAtRule(a *AtRule) {
+	f.writeIndent()
+	f.buf.WriteString("@")
+	f.buf.WriteString(a.Name)
+	f.buf.WriteString(" ")
+	f.buf.WriteString(a.Prelude)
+	f.buf.WriteString(";\n")
+}
+
 // formatComment formats a comment node
 func (f *Formatter) formatComment(c *Comment) {
 	f.writeIndent()
@@ -71,8 +87,7 @@ func (f *Formatter) formatDecl(d *Decl) {
 		// Variable assignment - output but don't store
 		f.writeIndent()
 		f.buf.WriteString(d.Key)
-		f.buf.WriteString(": ")
-		f.buf.WriteString(d.Value)
+		f.writeValue(d.Key, d.Value)
 		// Add semicolon if not present
 		if !strings.HasSuffix(d.Value, ";") {
 			f.buf.WriteString(";")
@@ -83,8 +98,7 @@ func (f *Formatter) formatDecl(d *Decl) {
 
 	f.writeIndent()
 	f.buf.WriteString(d.Key)
-	f.buf.WriteString(": ")
-	f.buf.WriteString(d.Value)
+	f.writeValue(d.Key, d.Value)
 
 	// Add semicolon if not present
 	if !strings.HasSuffix(d.Value, ";") {
@@ -94,6 +108,61 @@ func (f *Formatter) formatDecl(d *Decl) {
 	f.buf.WriteString("\n")
 }
 
+// writeValue writes ": value", putting each item of a comma-separated
+// value on its own indented line when the declaration exceeds LineWidth
+func (f *Formatter) writeValue(key, value string) {
+	parts := splitValueList(value)
+	if f.LineWidth <= 0 || len(parts) < 2 || f.indent*2+len(key)+len(value)+3 <= f.LineWidth {
+		f.buf.WriteString(": ")
+		f.buf.WriteString(value)
+		return
+	}
+
+	f.buf.WriteString(":")
+	f.indent++
+	for i, part := range parts {
+		if i > 0 {
+			f.buf.WriteString(",")
+		}
+		f.buf.WriteString("\n")
+		f.writeIndent()
+		f.buf.WriteString(part)
+	}
+	f.indent--
+}
+
+// splitValueList splits a value on top-level commas, the ones outside
+// of parentheses, quoted strings and url(...).
+func splitValueList(value string) []string {
+	var parts []string
+	depth, start := 0, 0
+	quoteChar := byte(0)
+
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case quoteChar != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quoteChar {
+				quoteChar = 0
+			}
+		case isURLStart(value, i):
+			i = urlEnd(value, i) - 1
+		case ch == '"' || ch == '\'':
+			quoteChar = ch
+		case ch == '(':
+			depth++
+		case ch == ')':
+			depth--
+		case ch == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(value[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(value[start:]))
+}
+
 // formatBlock formats a block node with nested children
 func (f *Formatter) formatBlock(b *Block) {
 	// Skip parametric mixin definitions (they're only invoked, not output)
@@ -109,7 +178,7 @@ func (f *Formatter) formatBlock(b *Block) {
 			f.buf.WriteString(",\n")
 			f.writeIndent()
 		}
-		f.buf.WriteString(name)
+		f.buf.WriteString(FormatSelector(name))
 	}
 
 	f.buf.WriteString(" {\n")
@@ -132,6 +201,69 @@ func (f *Formatter) formatBlock(b *Block) {
 	}
 }
 
+// FormatSelector puts single spaces around the >, + and ~ combinators and
+// collapses other whitespace, so ".a>.b" and ".a  >  .b" become ".a > .b".
+// Parentheses, attribute selectors and strings are copied as they are,
+// keeping :nth-child(2n+1) and [class~=x] intact.
+func FormatSelector(sel string) string {
+	var buf strings.Builder
+	depth := 0
+	space := false
+	for i := 0; i < len(sel); i++ {
+		ch := sel[i]
+		switch {
+		case ch == '\\' && i+1 < len(sel):
+			buf.WriteByte(ch)
+			i++
+			buf.WriteByte(sel[i])
+			continue
+		case ch == '"' || ch == '\'':
+			end := i + 1
+			for end < len(sel) && sel[end] != ch {
+				if sel[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(sel) {
+				end = len(sel) - 1
+			}
+			if space {
+				buf.WriteByte(' ')
+				space = false
+			}
+			buf.WriteString(sel[i : end+1])
+			i = end
+			continue
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case depth > 0:
+		case ch == ' ' || ch == '\t' || ch == '\n':
+			space = buf.Len() > 0
+			continue
+		case ch == '>' || ch == '+' || ch == '~':
+			if buf.Len() > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteByte(ch)
+			buf.WriteByte(' ')
+			space = false
+			for i+1 < len(sel) && (sel[i+1] == ' ' || sel[i+1] == '\t' || sel[i+1] == '\n') {
+				i++
+			}
+			continue
+		}
+		if space {
+			buf.WriteByte(' ')
+			space = false
+		}
+		buf.WriteByte(ch)
+	}
+	return strings.TrimSpace(buf.String())
+}
+
 // formatMixinCall formats a mixin call
 func (f *Formatter) formatMixinCall(m *MixinCall) {
 	f.writeIndent()
@@ -141,7 +273,19 @@ func (f *Formatter) formatMixinCall(m *MixinCall) {
 		if i > 0 {
 			f.buf.WriteString("; ")
 		}
-		f.buf.WriteString(arg)
+		ruleset, ok := m.Rulesets[i]
+		if !ok {
+			f.buf.WriteString(arg)
+			continue
+		}
+		f.buf.WriteString("{\n")
+		f.indent++
+		for _, child := range ruleset.Children {
+			f.formatNode(child)
+		}
+		f.indent--
+		f.writeIndent()
+		f.buf.WriteString("}")
 	}
 	f.buf.WriteString(");\n")
 }
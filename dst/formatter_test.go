@@ -0,0 +1,130 @@
+package dst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+func TestFormatSelector(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{".a>.b", ".a > .b"},
+		{".a  >  .b", ".a > .b"},
+		{".a>b", ".a > b"},
+		{".a+.b", ".a + .b"},
+		{".a   +   .b", ".a + .b"},
+		{".a~.b", ".a ~ .b"},
+		{".a ~  .b", ".a ~ .b"},
+		{"&>.b", "& > .b"},
+		{">li", "> li"},
+		{".a   .b", ".a .b"},
+		{".a > .b", ".a > .b"},
+		{"li:nth-child(2n+1)", "li:nth-child(2n+1)"},
+		{"[class~=x]>a", "[class~=x] > a"},
+		{"a[title=\"a > b\"]+b", "a[title=\"a > b\"] + b"},
+		{".a:not(.b>.c)", ".a:not(.b>.c)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, FormatSelector(tt.input))
+		})
+	}
+}
+
+func TestFormatterLineWidth(t *testing.T) {
+	src := `.a {
+  transition: opacity 0.3s ease-in-out, transform 0.3s cubic-bezier(0.4, 0, 0.2, 1), visibility 0s linear 0.3s;
+  background-image: linear-gradient(to right, rgba(255, 255, 255, 0) 0%, rgba(255, 255, 255, 0.8) 50%), url("a,b.png");
+  margin: 0 auto;
+  font-family: Arial, sans-serif;
+}
+`
+	file, err := NewParser(strings.NewReader(src)).Parse()
+	require.NoError(t, err)
+
+	f := NewFormatter()
+	f.LineWidth = 80
+	require.Equal(t, `.a {
+  transition:
+    opacity 0.3s ease-in-out,
+    transform 0.3s cubic-bezier(0.4, 0, 0.2, 1),
+    visibility 0s linear 0.3s;
+  background-image:
+    linear-gradient(to right, rgba(255, 255, 255, 0) 0%, rgba(255, 255, 255, 0.8) 50%),
+    url("a,b.png");
+  margin: 0 auto;
+  font-family: Arial, sans-serif;
+}
+
+`, f.Format(file))
+
+	f.LineWidth = 0
+	require.Equal(t, src+"\n", f.Format(file))
+}
+
+func TestFormatterLineWidthRoundTrip(t *testing.T) {
+	src := `@shadows: 0 1px 2px rgba(0, 0, 0, 0.2), 0 2px 4px rgba(0, 0, 0, 0.2), inset 0 0 1px red;
+.a {
+  transition: opacity 0.3s ease-in-out, transform 0.3s cubic-bezier(0.4, 0, 0.2, 1), visibility 0s linear 0.3s;
+  background: url(data:image/png;base64,AAAA) no-repeat, linear-gradient(to right, red 0%, blue 100%), white;
+  margin: 0 auto;
+}
+`
+	decls := func(file *File) []string {
+		var result []string
+		var walk func(nodes []Node)
+		walk = func(nodes []Node) {
+			for _, node := range nodes {
+				switch n := node.(type) {
+				case *Decl:
+					result = append(result, n.Key+": "+n.Value)
+				case *Block:
+					walk(n.Children)
+				}
+			}
+		}
+		walk(file.Nodes)
+		return result
+	}
+
+	file, err := NewParser(strings.NewReader(src)).Parse()
+	require.NoError(t, err)
+
+	f := NewFormatter()
+	f.LineWidth = 60
+	formatted := f.Format(file)
+	require.Contains(t, formatted, "transition:\n    opacity")
+
+	// The wrapped output parses back to the same declarations
+	reparsed, err := NewParser(strings.NewReader(formatted)).Parse()
+	require.NoError(t, err)
+	require.Equal(t, decls(file), decls(reparsed))
+
+	// Formatting the output again doesn't change it
+	require.Equal(t, formatted, f.Format(reparsed))
+}
+
+func TestSplitValueList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"a", []string{"a"}},
+		{"a, b", []string{"a", "b"}},
+		{"rgba(0, 0, 0, 0.5) 0 1px, red", []string{"rgba(0, 0, 0, 0.5) 0 1px", "red"}},
+		{`"a, b", c`, []string{`"a, b"`, "c"}},
+		{"url(data:image/png;base64,AA), none", []string{"url(data:image/png;base64,AA)", "none"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, splitValueList(tt.input))
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package dst
+
+import "github.com/titpetric/lessgo/internal/strings"
+
+// TokenType is the kind of a lexical token
+type TokenType string
+
+const (
+	TokenSpace            TokenType = "space"             // spaces, tabs and newlines
+	TokenCommentOneline   TokenType = "comment-oneline"   // // comment, up to the end of the line
+	TokenCommentMultiline TokenType = "comment-multiline" // /* comment */
+	TokenString           TokenType = "string"            // "quoted" or 'quoted'
+	TokenAtKeyword        TokenType = "at-keyword"        // @media, @var, @@var or @{var}
+	TokenHash             TokenType = "hash"              // #fff or #id
+	TokenNumber           TokenType = "number"            // 10, 1.5em, .5 or 50%
+	TokenIdent            TokenType = "ident"             // color, sans-serif, url
+	TokenPunct            TokenType = "punct"             // any other single character
+)
+
+// Token is a lexical token with its position in the source.
+// Line and Column are 1-based, Column and Offset count bytes.
+type Token struct {
+	Type   TokenType
+	Text   string
+	Offset int
+	Line   int
+	Column int
+}
+
+// Lex splits src into tokens for tooling like syntax highlighters.
+// Every byte of src belongs to a token, so joining the token texts
+// gives back the source. Unterminated strings and comments end at
+// the end of the line or input.
+func Lex(src string) []Token {
+	var tokens []Token
+	line, column := 1, 1
+	parenDepth := 0
+
+	for i := 0; i < len(src); {
+		ch := src[i]
+		next := byte(0)
+		if i+1 < len(src) {
+			next = src[i+1]
+		}
+
+		typ, end := TokenPunct, i+1
+		switch {
+		case isSpaceByte(ch):
+			typ, end = TokenSpace, scanWhile(src, i, isSpaceByte)
+
+		case ch == '/' && next == '*':
+			typ, end = TokenCommentMultiline, len(src)
+			if j := strings.Index(src[i+2:], "*/"); j != -1 {
+				end = i + 2 + j + 2
+			}
+
+		case ch == '/' && next == '/' && parenDepth == 0:
+			typ, end = TokenCommentOneline, len(src)
+			if j := strings.IndexByte(src[i:], '\n'); j != -1 {
+				end = i + j
+			}
+
+		case ch == '"' || ch == '\'':
+			typ, end = TokenString, i+1
+			for end < len(src) && src[end] != ch && src[end] != '\n' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end < len(src) && src[end] == ch {
+				end++
+			}
+			end = min(end, len(src))
+
+		case ch == '@' && next == '{':
+			typ, end = TokenAtKeyword, len(src)
+			if j := strings.IndexByte(src[i:], '}'); j != -1 {
+				end = i + j + 1
+			}
+
+		case ch == '@':
+			typ, end = TokenAtKeyword, scanWhile(src, i+1, func(b byte) bool { return b == '@' })
+			end = scanWhile(src, end, isNameByte)
+
+		case ch == '#' && next != 0 && isNameByte(next):
+			typ, end = TokenHash, scanWhile(src, i+1, isNameByte)
+
+		case isDigit(ch) || ch == '.' && isDigit(next):
+			typ, end = TokenNumber, scanWhile(src, i, func(b byte) bool { return isDigit(b) || b == '.' })
+			if end < len(src) && src[end] == '%' {
+				end++
+			} else {
+				end = scanWhile(src, end, isNameByte)
+			}
+
+		case isNameByte(ch) && !isDigit(ch):
+			typ, end = TokenIdent, scanWhile(src, i, isNameByte)
+
+		case ch == '(':
+			parenDepth++
+
+		case ch == ')':
+			if parenDepth > 0 {
+				parenDepth--
+			}
+		}
+
+		text := src[i:end]
+		tokens = append(tokens, Token{Type: typ, Text: text, Offset: i, Line: line, Column: column})
+
+		if n := strings.Count(text, "\n"); n > 0 {
+			line += n
+			column = len(text) - strings.LastIndex(text, "\n")
+		} else {
+			column += len(text)
+		}
+		i = end
+	}
+	return tokens
+}
+
+// scanWhile returns the offset of the first byte from start not matching fn
+func scanWhile(src string, start int, fn func(byte) bool) int {
+	for start < len(src) && fn(src[start]) {
+		start++
+	}
+	return start
+}
+
+// isNameByte checks if b can be part of an identifier, non-ASCII bytes included
+func isNameByte(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || isDigit(b) || b == '-' || b == '_' || b >= 0x80
+}
+
+// isSpaceByte checks if b is whitespace
+func isSpaceByte(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+// isDigit checks if b is an ASCII digit
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
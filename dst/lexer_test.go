@@ -0,0 +1,49 @@
+package dst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLex(t *testing.T) {
+	src := "// theme\n@c: #fff;\n.a-@{n} {\n  /* note */\n  width: 1.5em;\n  b: url(//x.png) \"q\";\n}\n"
+
+	tokens := Lex(src)
+
+	var joined string
+	for _, tok := range tokens {
+		joined += tok.Text
+	}
+	require.Equal(t, src, joined)
+
+	var got []Token
+	for _, tok := range tokens {
+		if tok.Type != TokenSpace && tok.Type != TokenPunct {
+			got = append(got, tok)
+		}
+	}
+	require.Equal(t, []Token{
+		{Type: TokenCommentOneline, Text: "// theme", Offset: 0, Line: 1, Column: 1},
+		{Type: TokenAtKeyword, Text: "@c", Offset: 9, Line: 2, Column: 1},
+		{Type: TokenHash, Text: "#fff", Offset: 13, Line: 2, Column: 5},
+		{Type: TokenIdent, Text: "a-", Offset: 20, Line: 3, Column: 2},
+		{Type: TokenAtKeyword, Text: "@{n}", Offset: 22, Line: 3, Column: 4},
+		{Type: TokenCommentMultiline, Text: "/* note */", Offset: 31, Line: 4, Column: 3},
+		{Type: TokenIdent, Text: "width", Offset: 44, Line: 5, Column: 3},
+		{Type: TokenNumber, Text: "1.5em", Offset: 51, Line: 5, Column: 10},
+		{Type: TokenIdent, Text: "b", Offset: 60, Line: 6, Column: 3},
+		{Type: TokenIdent, Text: "url", Offset: 63, Line: 6, Column: 6},
+		{Type: TokenIdent, Text: "x", Offset: 69, Line: 6, Column: 12},
+		{Type: TokenIdent, Text: "png", Offset: 71, Line: 6, Column: 14},
+		{Type: TokenString, Text: `"q"`, Offset: 76, Line: 6, Column: 19},
+	}, got)
+}
+
+func TestLexUnterminated(t *testing.T) {
+	require.Equal(t, []Token{
+		{Type: TokenString, Text: `"open`, Offset: 0, Line: 1, Column: 1},
+		{Type: TokenSpace, Text: "\n", Offset: 5, Line: 1, Column: 6},
+		{Type: TokenCommentMultiline, Text: "/* x", Offset: 6, Line: 2, Column: 1},
+	}, Lex("\"open\n/* x"))
+}
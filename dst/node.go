@@ -19,6 +19,7 @@ const (
 	TypeBlockVariable NodeType = "block_variable" // Block variable (@var: { ... };)
 	TypeEach          NodeType = "each"           // Each loop (each(list, { ... });)
 	TypeImport        NodeType = "import"         // CSS @import passthrough
+	TypeAtRule        NodeType = "at_rule"        // Statement at-rule (@charset, @namespace)
 )
 
 // Decl represents a CSS declaration (property: value;)
@@ -26,6 +27,8 @@ type Decl struct {
 	SelNames []string // selectors (e.g., ".class", "h1 span")
 	Key      string   // property name (e.g., "color")
 	Value    string   // property value (e.g., "#000")
+	Imported bool     // declared in an imported file
+	Line     int      // line in the parsed file, 0 if unknown or imported
 }
 
 func (d *Decl) Names() []string { return d.SelNames }
@@ -35,6 +38,7 @@ func (d *Decl) Type() NodeType  { return TypeDecl }
 type Comment struct {
 	Text      string // comment text without // or /* */
 	Multiline bool   // true if /* */ style, false if // style
+	Imported  bool   // written in an imported file
 }
 
 func (c *Comment) Names() []string { return nil }
@@ -56,6 +60,7 @@ type Block struct {
 	Parent          *Block   // parent block for & resolution
 	Params          []string // mixin parameters (e.g., "@v", "@color")
 	Guard           *Guard   // Guard conditions for mixin
+	Line            int      // line in the parsed file, 0 if unknown or imported
 }
 
 func (b *Block) Names() []string {
@@ -68,6 +73,12 @@ func (b *Block) Type() NodeType { return TypeBlock }
 type MixinCall struct {
 	Name string   // mixin name (e.g., ".mixin")
 	Args []string // arguments (e.g., ["10px"], ["@color", "blue"])
+
+	Important bool // called with !important, applied to every expanded declaration
+
+	Rulesets map[int]*BlockVariable // detached rulesets passed inline, by argument index
+
+	Line int // line in the parsed file, 0 if unknown or imported
 }
 
 func (m *MixinCall) Names() []string { return nil }
@@ -94,12 +105,22 @@ func (e *Each) Type() NodeType  { return TypeEach }
 
 // Import represents a CSS @import statement that should pass through to output
 type Import struct {
-	Path string // the import path (URL or file reference)
+	Path  string // the import path (URL or file reference)
+	Media string // the media condition following the path, if any
 }
 
 func (i *Import) Names() []string { return nil }
 func (i *Import) Type() NodeType  { return TypeImport }
 
+// AtRule represents a statement at-rule without a block, like @charset or @namespace
+type AtRule struct {
+	Name    string // the at-rule keyword without @, e.g. "namespace"
+	Prelude string // everything after the keyword, e.g. svg url(http://www.w3.org/2000/svg)
+}
+
+func (a *AtRule) Names() []string { return nil }
+func (a *AtRule) Type() NodeType  { return TypeAtRule }
+
 // File represents the entire parsed .less file
 type File struct {
 	Nodes []Node
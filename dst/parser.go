@@ -2,9 +2,12 @@ package dst
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 	"io/fs"
 	"os"
+	"path"
 
 	"github.com/titpetric/lessgo/expression"
 	"github.com/titpetric/lessgo/internal/strings"
@@ -14,41 +17,47 @@ import (
 type Parser struct {
 	scanner *bufio.Scanner
 	line    string
+	lineNo  int   // number of the current sanitized line
+	lines   []int // source line of each sanitized line
 	eof     bool
+	err     error // error reading or validating the input
 	fs      fs.FS // filesystem for resolving imports
 
 	// Pre-allocated buffers for zero-alloc splitting
 	selectorBuf []string // For selector splitting (comma-separated)
 	declBuf     []string // For declaration splitting (semicolon-separated)
-	argBuf      []string // For argument splitting (comma-separated)
 }
 
 // NewParser creates a new parser from a reader with OS filesystem
 func NewParser(r io.Reader) *Parser {
-	return &Parser{
-		scanner:     bufio.NewScanner(SanitizeReader(r)),
-		eof:         false,
-		fs:          os.DirFS("."),
-		selectorBuf: make([]string, 0, 16),
-		declBuf:     make([]string, 0, 32),
-		argBuf:      make([]string, 0, 16),
-	}
+	return NewParserWithFS(r, os.DirFS("."))
 }
 
 // NewParserWithFS creates a new parser with a custom filesystem
 func NewParserWithFS(r io.Reader, filesystem fs.FS) *Parser {
+	data, err := io.ReadAll(r)
+	if err == nil {
+		// Unclosed blocks and comments are reported before parsing
+		err = checkUnterminated(normalizeLineEndings(data))
+	}
+	sanitized := SanitizeBytes(data)
 	return &Parser{
-		scanner:     bufio.NewScanner(SanitizeReader(r)),
+		scanner:     bufio.NewScanner(bytes.NewReader(sanitized)),
+		lines:       sourceLines(data, sanitized),
 		eof:         false,
+		err:         err,
 		fs:          filesystem,
 		selectorBuf: make([]string, 0, 16),
 		declBuf:     make([]string, 0, 32),
-		argBuf:      make([]string, 0, 16),
 	}
 }
 
 // Parse parses the entire .less file into a File AST
 func (p *Parser) Parse() (*File, error) {
+	if p.err != nil {
+		return nil, p.err
+	}
+
 	file := &File{}
 
 	for p.scan() {
@@ -93,12 +102,20 @@ func (p *Parser) Parse() (*File, error) {
 
 		if strings.HasPrefix(line, "@import") {
 
-			p.parseImport(file, line)
+			if err := p.parseImport(file, line); err != nil {
+				return nil, err
+			}
 
 			continue
 
 		}
 
+		// Statement at-rules (@charset "UTF-8"; @namespace svg url(...);)
+		if atRule := parseAtRule(line); atRule != nil {
+			file.Nodes = append(file.Nodes, atRule)
+			continue
+		}
+
 		// Block variable definition (@name: { ... };)
 		if strings.HasPrefix(line, "@") && strings.Contains(line, ":") && strings.Contains(line, "{") {
 
@@ -178,7 +195,7 @@ func (p *Parser) Parse() (*File, error) {
 						args = append(args, strings.TrimSpace(arg))
 					}
 				}
-				file.Nodes = append(file.Nodes, &MixinCall{Name: firstPart, Args: args})
+				file.Nodes = append(file.Nodes, &MixinCall{Name: firstPart, Args: args, Line: p.sourceLine()})
 			}
 
 		}
@@ -188,9 +205,32 @@ func (p *Parser) Parse() (*File, error) {
 	return file, nil
 }
 
+// markImportedComments marks the comments in nodes and nested blocks as
+// imported, so the renderer can leave out license banners of imported files.
+// Source lines are cleared, they aren't lines of the importing file.
+func markImportedComments(nodes []Node) {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Comment:
+			n.Imported = true
+		case *Decl:
+			n.Line = 0
+		case *Block:
+			n.Line = 0
+			markImportedComments(n.Children)
+		case *MixinCall:
+			n.Line = 0
+		case *BlockVariable:
+			markImportedComments(n.Children)
+		case *Each:
+			markImportedComments(n.Children)
+		}
+	}
+}
+
 // parseImport parses an import statement like @import "file.less";
 
-func (p *Parser) parseImport(file *File, line string) {
+func (p *Parser) parseImport(file *File, line string) error {
 	// Extract the file path from @import "path";
 
 	// Match patterns like @import "path/file.less"; or @import 'path/file.less';
@@ -205,30 +245,41 @@ func (p *Parser) parseImport(file *File, line string) {
 
 	line = strings.TrimSpace(line)
 
-	// Remove quotes
+	// Remove quotes, anything after the path is a media condition: @import "print.less" print;
 
-	var filePath string
+	var filePath, media string
 
-	if strings.HasPrefix(line, "\"") && strings.HasSuffix(line, "\"") {
-		filePath = line[1 : len(line)-1]
-	} else if strings.HasPrefix(line, "'") && strings.HasSuffix(line, "'") {
-		filePath = line[1 : len(line)-1]
-	} else {
-		return
+	if line == "" || (line[0] != '"' && line[0] != '\'') {
+		return nil
+	}
+	end := strings.IndexByte(line[1:], line[0])
+	if end == -1 {
+		return nil
 	}
+	filePath = line[1 : end+1]
+	media = strings.TrimSpace(line[end+2:])
 
 	// Check if this is a URL import (http://, https://, or protocol-relative //)
 	// These should pass through to CSS output, not be processed
 	if strings.HasPrefix(filePath, "http://") || strings.HasPrefix(filePath, "https://") || strings.HasPrefix(filePath, "//") {
-		file.Nodes = append(file.Nodes, &Import{Path: filePath})
-		return
+		file.Nodes = append(file.Nodes, &Import{Path: filePath, Media: media})
+		return nil
 	}
 
-	// Open the file from the filesystem
+	// Open the file from the filesystem, a cache-busting ?query or #fragment
+	// doesn't belong to the file name: @import "theme.less?v=2";
+
+	if i := strings.IndexAny(filePath, "?#"); i != -1 {
+		filePath = filePath[:i]
+	}
 
-	f, err := p.fs.Open(filePath)
+	f, err := p.openImport(filePath)
 	if err != nil {
-		return
+		// Plain CSS imports that aren't available locally are skipped
+		if strings.HasSuffix(filePath, ".css") {
+			return nil
+		}
+		return err
 	}
 
 	defer f.Close()
@@ -239,12 +290,106 @@ func (p *Parser) parseImport(file *File, line string) {
 
 	importedFile, err := importedParser.Parse()
 	if err != nil {
-		return
+		return fmt.Errorf("import %q: %w", filePath, err)
+	}
+
+	// Mark imported declarations, so the importer overriding them can be told apart
+
+	for _, node := range importedFile.Nodes {
+		if decl, ok := node.(*Decl); ok {
+			decl.Imported = true
+		}
+	}
+	markImportedComments(importedFile.Nodes)
+
+	nodes := importedFile.Nodes
+	if media != "" {
+		nodes = wrapMedia(nodes, media)
 	}
 
 	// Then, prepend imported nodes to file nodes
 
-	file.Nodes = append(importedFile.Nodes, file.Nodes...)
+	file.Nodes = append(nodes, file.Nodes...)
+	return nil
+}
+
+// wrapMedia puts the rules of a file imported with a media condition into a
+// single @media block. Variables and detached rulesets stay at the top level,
+// so they remain visible to the importing file.
+func wrapMedia(nodes []Node, media string) []Node {
+	result := make([]Node, 0, len(nodes)+1)
+	wrapper := &Block{SelNames: []string{"@media " + media}}
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *Decl:
+			if strings.HasPrefix(n.Key, "@") && strings.TrimSpace(n.Value) != "()" {
+				result = append(result, n)
+				continue
+			}
+		case *BlockVariable, *Import, *AtRule:
+			result = append(result, n)
+			continue
+		}
+		wrapper.Children = append(wrapper.Children, node)
+	}
+	if len(wrapper.Children) == 0 {
+		return result
+	}
+	return append(result, wrapper)
+}
+
+// importCandidates returns the paths tried when resolving an import.
+// An import without an extension tries name.less, name/index.less
+// and name/name.less, following common bundler conventions.
+func importCandidates(filePath string) []string {
+	filePath = path.Clean(filePath)
+	if path.Ext(filePath) != "" {
+		return []string{filePath}
+	}
+	return []string{
+		filePath + ".less",
+		path.Join(filePath, "index.less"),
+		path.Join(filePath, path.Base(filePath)+".less"),
+	}
+}
+
+// openImport opens the first import candidate that exists in the filesystem
+func (p *Parser) openImport(filePath string) (fs.File, error) {
+	candidates := importCandidates(filePath)
+	for _, candidate := range candidates {
+		f, err := p.fs.Open(candidate)
+		if err != nil {
+			continue
+		}
+		if info, err := f.Stat(); err == nil && info.IsDir() {
+			f.Close()
+			continue
+		}
+		return f, nil
+	}
+	return nil, fmt.Errorf("import %q not found, tried %s", filePath, strings.Join(candidates, ", "))
+}
+
+// statementAtRules are at-rules without a block that are emitted verbatim,
+// e.g. a cascade layer order like @layer reset, base;
+var statementAtRules = []string{"charset", "namespace", "layer"}
+
+// parseAtRule parses a statement at-rule like @namespace svg url(...);
+func parseAtRule(line string) *AtRule {
+	if !strings.HasPrefix(line, "@") || !strings.HasSuffix(line, ";") {
+		return nil
+	}
+	for _, name := range statementAtRules {
+		rest, ok := strings.CutPrefix(line[1:], name)
+		if !ok || rest == "" || (rest[0] != ' ' && rest[0] != '\t') {
+			continue
+		}
+		return &AtRule{
+			Name:    name,
+			Prelude: strings.TrimSpace(strings.TrimSuffix(rest, ";")),
+		}
+	}
+	return nil
 }
 
 // parseBlock parses a selector block with nested nodes
@@ -304,6 +449,11 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 
 	selectorList := splitSelectorList(selectorStr)
 
+	// At-rule preludes like "@media screen, print" are a single condition
+	if strings.HasPrefix(trimmedSel, "@") {
+		selectorList = []string{trimmedSel}
+	}
+
 	for _, sel := range selectorList {
 
 		sel = strings.TrimSpace(sel)
@@ -352,6 +502,8 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 		IsMixinFunction: isMixinFunction,
 
 		Guard: guard,
+
+		Line: p.sourceLine(),
 	}
 
 	// Read nested content until closing }
@@ -396,6 +548,57 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 
 		}
 
+		// Scoped import, the imported rules are nested in this block
+		if strings.HasPrefix(line, "@import") {
+			scoped := &File{}
+			if err := p.parseImport(scoped, line); err != nil {
+				return nil, err
+			}
+			for _, node := range scoped.Nodes {
+				if nested, ok := node.(*Block); ok {
+					nested.Parent = block
+				}
+			}
+			block.Children = append(block.Children, scoped.Nodes...)
+			continue
+		}
+
+		// Custom property, the value may contain braces (e.g., "--x: { a: b };")
+		if decl := parseCustomProperty(line); decl != nil {
+			decl.Line = p.sourceLine()
+			block.Children = append(block.Children, decl)
+			continue
+		}
+
+		// Each function call, the children are rendered in this block
+		if strings.HasPrefix(line, "each(") {
+			each, err := p.parseEach(line)
+			if err != nil {
+				return nil, err
+			}
+			if each != nil {
+				block.Children = append(block.Children, each)
+				continue
+			}
+		}
+
+		// Mixin call with !important (e.g., ".mixin() !important;")
+		if call := parseImportantMixinCall(line); call != nil {
+			call.Line = p.sourceLine()
+			block.Children = append(block.Children, call)
+			continue
+		}
+
+		// Mixin call with a detached ruleset argument (e.g., ".m({ color: red; });")
+		call, err := p.parseRulesetMixinCall(line)
+		if err != nil {
+			return nil, err
+		}
+		if call != nil {
+			block.Children = append(block.Children, call)
+			continue
+		}
+
 		// Single-line block (e.g., "p { margin: 0; padding: 0; }")
 		// But skip if braces are part of @{...} interpolation
 		braceOpen, braceClose := findBlockBraces(line)
@@ -419,6 +622,8 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 				Parent: block,
 
 				Children: []Node{},
+
+				Line: p.sourceLine(),
 			}
 
 			// Parse declarations in the inline block (zero-alloc)
@@ -483,13 +688,12 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 
 					var args []string
 					if argsStr != "" {
-						strings.SplitCommaNoAlloc(argsStr, &p.argBuf)
-						// Make a copy since the buffer will be reused
-						args = make([]string, len(p.argBuf))
-						copy(args, p.argBuf)
+						for _, arg := range splitParameterList(argsStr) {
+							args = append(args, strings.TrimSpace(arg))
+						}
 					}
 
-					block.Children = append(block.Children, &MixinCall{Name: firstPart, Args: args})
+					block.Children = append(block.Children, &MixinCall{Name: firstPart, Args: args, Line: p.sourceLine()})
 
 				} else {
 
@@ -508,7 +712,7 @@ func (p *Parser) parseBlock(line string) (*Block, error) {
 
 			// Mixin call without parentheses (e.g., ".mixin;")
 			mixinName := strings.TrimSuffix(strings.TrimSpace(line), ";")
-			block.Children = append(block.Children, &MixinCall{Name: mixinName, Args: []string{}})
+			block.Children = append(block.Children, &MixinCall{Name: mixinName, Args: []string{}, Line: p.sourceLine()})
 
 		} else if strings.Contains(line, ":") && strings.HasSuffix(line, ";") {
 
@@ -555,27 +759,10 @@ func (p *Parser) parseBlockVariable(line string) (*BlockVariable, error) {
 	if strings.HasSuffix(rest, "};") {
 		// Single-line case
 		blockContent := rest[1 : len(rest)-2] // Remove { and };
-		blockVar := &BlockVariable{
+		return &BlockVariable{
 			Name:     varName,
-			Children: []Node{},
-		}
-
-		// Parse simple single-line declarations (zero-alloc)
-		strings.SplitByteNoAlloc(blockContent, ';', &p.declBuf)
-		for _, declStr := range p.declBuf {
-			if declStr == "" {
-				continue
-			}
-			if !strings.Contains(declStr, ":") {
-				continue
-			}
-			decl := p.parseDecl(declStr + ";")
-			if decl != nil {
-				blockVar.Children = append(blockVar.Children, decl)
-			}
-		}
-
-		return blockVar, nil
+			Children: p.parseInlineDecls(blockContent),
+		}, nil
 	}
 
 	// Multi-line case: we need to read until we find the closing };
@@ -618,6 +805,16 @@ func (p *Parser) parseBlockVariable(line string) (*BlockVariable, error) {
 			continue
 		}
 
+		// Nested rule, parsed so its closing brace doesn't end the ruleset
+		if strings.HasSuffix(line, "{") {
+			block, err := p.parseBlock(line)
+			if err != nil {
+				return nil, err
+			}
+			blockVar.Children = append(blockVar.Children, block)
+			continue
+		}
+
 		// Declaration
 		if strings.Contains(line, ":") && strings.HasSuffix(line, ";") {
 			decl := p.parseDecl(line)
@@ -630,6 +827,100 @@ func (p *Parser) parseBlockVariable(line string) (*BlockVariable, error) {
 	return blockVar, nil
 }
 
+// parseInlineDecls parses the declarations of a single-line ruleset body
+func (p *Parser) parseInlineDecls(content string) []Node {
+	nodes := []Node{}
+
+	// Parse simple single-line declarations (zero-alloc)
+	strings.SplitByteNoAlloc(content, ';', &p.declBuf)
+	for _, declStr := range p.declBuf {
+		if declStr == "" {
+			continue
+		}
+		if !strings.Contains(declStr, ":") {
+			continue
+		}
+		decl := p.parseDecl(declStr + ";")
+		if decl != nil {
+			nodes = append(nodes, decl)
+		}
+	}
+	return nodes
+}
+
+// parseRulesetMixinCall parses a mixin call passing a detached ruleset as
+// the last argument, e.g. ".m(10px; {", reading the ruleset up to "});".
+// Returns nil for other lines.
+func (p *Parser) parseRulesetMixinCall(line string) (*MixinCall, error) {
+	call, ok := strings.CutSuffix(line, "{")
+	call = strings.TrimSpace(call)
+	if !ok || call == "" || (call[0] != '.' && call[0] != '#') {
+		return nil, nil
+	}
+	parenIdx := strings.Index(call, "(")
+	if parenIdx == -1 || strings.ContainsAny(call[:parenIdx], ": {") {
+		return nil, nil
+	}
+	argsStr, ok := strings.CutSuffix(call[parenIdx+1:], ";")
+	if !ok {
+		argsStr, ok = strings.CutSuffix(argsStr, ",")
+	}
+	if !ok && strings.TrimSpace(argsStr) != "" {
+		return nil, nil
+	}
+
+	m := &MixinCall{Name: strings.TrimSpace(call[:parenIdx]), Args: []string{}, Line: p.sourceLine()}
+	for _, arg := range splitParameterList(argsStr) {
+		m.Args = append(m.Args, strings.TrimSpace(arg))
+	}
+
+	ruleset := &BlockVariable{Children: []Node{}}
+	m.Rulesets = map[int]*BlockVariable{len(m.Args): ruleset}
+	m.Args = append(m.Args, "{}")
+
+	// Nested rules consume their own closing brace, the ruleset ends with
+	// "});" which the sanitizer may have split into "}" and ");"
+	for p.scan() {
+		line := strings.TrimSpace(p.line)
+
+		if line == "" {
+			continue
+		}
+		if line == "}" || line == "});" {
+			break
+		}
+
+		// Single-line comment
+		if strings.HasPrefix(line, "//") {
+			ruleset.Children = append(ruleset.Children, &Comment{
+				Text:      strings.TrimPrefix(line, "//"),
+				Multiline: false,
+			})
+			continue
+		}
+
+		// Nested rule
+		if strings.HasSuffix(line, "{") {
+			block, err := p.parseBlock(line)
+			if err != nil {
+				return nil, err
+			}
+			ruleset.Children = append(ruleset.Children, block)
+			continue
+		}
+
+		if decl := p.parseDecl(line); decl != nil {
+			ruleset.Children = append(ruleset.Children, decl)
+		}
+	}
+
+	// Skip the ");" left over from a split "});"
+	if strings.TrimSpace(p.line) == "}" {
+		p.scan()
+	}
+	return m, nil
+}
+
 // parseEach parses an each() loop (each(list, { ... });)
 func (p *Parser) parseEach(line string) (*Each, error) {
 	// Check if this looks like each(list, { ... });
@@ -678,61 +969,22 @@ func (p *Parser) parseEach(line string) (*Each, error) {
 	}
 	blockStart += commaIdx
 
-	// Check if this is a single-line block
-	if strings.HasSuffix(line, "});") {
-		// Single-line case
-		blockContent := line[blockStart+1 : len(line)-2] // Remove { and });
-		blockContent = strings.TrimSpace(blockContent)
-
-		// Parse block selectors and content
-		// For now, we just store the raw block content for multi-line processing
-		// but we need to read it as nested blocks/declarations
-		// This is simplified - in reality we'd need to recursively parse
-		return each, nil
-	}
-
-	// Multi-line case: read until we find the closing });
-	// Read content after opening {
-	firstLineContent := strings.TrimSpace(line[blockStart+1:])
-	if firstLineContent != "" && firstLineContent != "}" && !strings.HasPrefix(firstLineContent, "}") {
-		// This could be a selector or declaration on the first line
-		// For now, store it for parsing
+	// The body is parsed like a block, the sanitizer has already split
+	// a single-line body like { .x { a: b } } into lines
+	body, err := p.parseBlock(line[blockStart:])
+	if err != nil {
+		return nil, err
 	}
-
-	// Read remaining lines until we find });
-	for p.scan() {
-		line := strings.TrimSpace(p.line)
-
-		if line == "" {
-			continue
-		}
-
-		// Check for closing });
-		if line == "});" || strings.HasSuffix(line, "});") {
-			break
-		}
-
-		// Skip comments for now
-		if strings.HasPrefix(line, "//") {
-			continue
+	for _, node := range body.Children {
+		if nested, ok := node.(*Block); ok {
+			nested.Parent = nil
 		}
+	}
+	each.Children = body.Children
 
-		// Try to parse as block selector
-		if strings.Contains(line, "{") {
-			block, err := p.parseBlock(line)
-			if err != nil {
-				return nil, err
-			}
-			if block != nil {
-				each.Children = append(each.Children, block)
-			}
-		} else if strings.Contains(line, ":") && strings.HasSuffix(line, ";") {
-			// Declaration
-			decl := p.parseDecl(line)
-			if decl != nil {
-				each.Children = append(each.Children, decl)
-			}
-		}
+	// Skip the ")" or ");" left over from a split "});"
+	if strings.TrimSpace(p.line) == "}" {
+		p.scan()
 	}
 
 	return each, nil
@@ -789,20 +1041,91 @@ func (p *Parser) parseDecl(line string) *Decl {
 		Key: key,
 
 		Value: value,
+
+		Line: p.sourceLine(),
+	}
+}
+
+// parseCustomProperty parses a "--name: value;" declaration. The value is an
+// opaque token stream and is kept as written. Returns nil for other lines.
+func parseCustomProperty(line string) *Decl {
+	if !strings.HasPrefix(line, "--") {
+		return nil
+	}
+
+	key, value, ok := strings.Cut(strings.TrimSuffix(line, ";"), ":")
+	if !ok || strings.ContainsAny(key, "{ ") && !strings.Contains(key, "@{") {
+		return nil
+	}
+
+	return &Decl{
+		SelNames: []string{},
+		Key:      strings.TrimSpace(key),
+		Value:    strings.TrimSpace(value),
+	}
+}
+
+// parseImportantMixinCall parses a ".mixin() !important;" or ".mixin !important;"
+// call. Returns nil for other lines.
+func parseImportantMixinCall(line string) *MixinCall {
+	call, ok := strings.CutSuffix(line, ";")
+	if !ok {
+		return nil
+	}
+	call, ok = strings.CutSuffix(strings.TrimSpace(call), "!important")
+	call = strings.TrimSpace(call)
+	if !ok || call == "" || (call[0] != '.' && call[0] != '#') {
+		return nil
+	}
+
+	name, argsStr := call, ""
+	if parenIdx := strings.Index(call, "("); parenIdx != -1 {
+		if !strings.HasSuffix(call, ")") {
+			return nil
+		}
+		name = strings.TrimSpace(call[:parenIdx])
+		argsStr = strings.TrimSpace(call[parenIdx+1 : len(call)-1])
 	}
+	if strings.ContainsAny(name, ": {") {
+		return nil
+	}
+
+	args := []string{}
+	if argsStr != "" {
+		for _, arg := range splitParameterList(argsStr) {
+			args = append(args, strings.TrimSpace(arg))
+		}
+	}
+	return &MixinCall{Name: name, Args: args, Important: true}
 }
 
 // normalizeCommas ensures each comma in a value is followed by a space.
 // Handles nested functions (parentheses) and respects quoted strings.
+// The contents of url(...) are kept as is, so data URIs aren't changed.
 func normalizeCommas(value string) string {
 	var result strings.Builder
 	inQuotes := false
-	quoteChar := rune(0)
-	parenDepth := 0
+	quoteChar := byte(0)
+
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+
+		if !inQuotes && isURLStart(value, i) {
+			end := urlEnd(value, i)
+			result.WriteString(value[i:end])
+			i = end - 1
+			continue
+		}
+
+		// Escaped characters in strings are copied as is, e.g. "\\"
+		if inQuotes && ch == '\\' && i+1 < len(value) {
+			result.WriteString(value[i : i+2])
+			i++
+			continue
+		}
 
-	for i, ch := range value {
 		// Track quoted strings
-		if (ch == '"' || ch == '\'') && (i == 0 || value[i-1] != '\\') {
+		if (ch == '"' || ch == '\'') && (inQuotes || i == 0 || value[i-1] != '\\') {
 			if !inQuotes {
 				inQuotes = true
 				quoteChar = ch
@@ -812,22 +1135,13 @@ func normalizeCommas(value string) string {
 			}
 		}
 
-		// Track parenthesis depth (for nested functions)
-		if !inQuotes {
-			if ch == '(' {
-				parenDepth++
-			} else if ch == ')' {
-				parenDepth--
-			}
-		}
-
-		result.WriteRune(ch)
+		result.WriteByte(ch)
 
 		// After a comma, ensure there's a space (if not in quotes and inside/outside parens)
 		if ch == ',' && !inQuotes {
 			// Look ahead to see if next char is not already a space
 			if i+1 < len(value) && value[i+1] != ' ' {
-				result.WriteRune(' ')
+				result.WriteByte(' ')
 			}
 		}
 	}
@@ -835,6 +1149,39 @@ func normalizeCommas(value string) string {
 	return result.String()
 }
 
+// isURLStart reports whether a url( function starts at value[i]
+func isURLStart(value string, i int) bool {
+	if i+4 > len(value) || !strings.EqualFold(value[i:i+4], "url(") {
+		return false
+	}
+	if i == 0 {
+		return true
+	}
+	prev := value[i-1]
+	return !(prev >= 'a' && prev <= 'z' || prev >= 'A' && prev <= 'Z' || prev >= '0' && prev <= '9' || prev == '-' || prev == '_')
+}
+
+// urlEnd returns the index after the parenthesis closing the url( at value[i],
+// or len(value) if it isn't closed. Quoted contents may contain parentheses.
+func urlEnd(value string, i int) int {
+	quote := byte(0)
+	for j := i + 4; j < len(value); j++ {
+		switch ch := value[j]; {
+		case quote != 0:
+			if ch == '\\' {
+				j++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ')':
+			return j + 1
+		}
+	}
+	return len(value)
+}
+
 // readMultilineComment reads a multi-line comment block
 
 func (p *Parser) readMultilineComment(comment *Comment, startLine string) {
@@ -893,10 +1240,19 @@ func (p *Parser) scan() bool {
 	}
 
 	p.line = p.scanner.Text()
+	p.lineNo++
 
 	return true
 }
 
+// sourceLine returns the line in the source file of the current line
+func (p *Parser) sourceLine() int {
+	if p.lineNo < 1 || p.lineNo > len(p.lines) {
+		return 0
+	}
+	return p.lines[p.lineNo-1]
+}
+
 // containsRealBrace checks if a line contains an actual opening brace (not from interpolation)
 // This is used to detect multi-line nested blocks
 func containsRealBrace(line string) bool {
@@ -997,12 +1353,17 @@ func splitSelectorList(selectorStr string) []string {
 	return result
 }
 
-// splitParameterList splits a parameter string by commas, respecting @{...} interpolation blocks and (...)
+// splitParameterList splits a parameter string by commas, respecting @{...} interpolation blocks and (...).
+// If the list contains a top-level ';', semicolons are used as the separator instead (LESS semantics).
 func splitParameterList(paramStr string) []string {
 	var result []string
 	var current strings.Builder
 	inInterpolation := false
 	parenDepth := 0
+	separator := byte(',')
+	if hasTopLevelSemicolon(paramStr) {
+		separator = ';'
+	}
 
 	for i := 0; i < len(paramStr); i++ {
 		if paramStr[i] == '@' && i+1 < len(paramStr) && paramStr[i+1] == '{' {
@@ -1019,7 +1380,7 @@ func splitParameterList(paramStr string) []string {
 		} else if paramStr[i] == ')' {
 			parenDepth--
 			current.WriteByte(')')
-		} else if !inInterpolation && parenDepth == 0 && paramStr[i] == ',' {
+		} else if !inInterpolation && parenDepth == 0 && paramStr[i] == separator {
 			result = append(result, current.String())
 			current.Reset()
 		} else {
@@ -1033,3 +1394,21 @@ func splitParameterList(paramStr string) []string {
 
 	return result
 }
+
+// hasTopLevelSemicolon reports whether s contains a ';' outside of parentheses
+func hasTopLevelSemicolon(s string) bool {
+	parenDepth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			parenDepth++
+		case ')':
+			parenDepth--
+		case ';':
+			if parenDepth == 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -344,6 +344,7 @@ func (p *ParserNoAlloc) parseImportNoAlloc(file *File, line string) {
 	}
 
 	// Prepend imported nodes
+	markImportedComments(importedFile.Nodes)
 	file.Nodes = append(importedFile.Nodes, file.Nodes...)
 }
 
@@ -528,8 +529,8 @@ func (p *ParserNoAlloc) parseEachNoAlloc(line string) (*Each, error) {
 			continue
 		}
 
-		// Check for closing });
-		if trimmedLine == "});" || strings.HasSuffix(trimmedLine, "});") {
+		// Check for closing });, the sanitizer may have split it into "}" and ");"
+		if trimmedLine == "});" || strings.HasSuffix(trimmedLine, "});") || trimmedLine == ");" || trimmedLine == ")" {
 			break
 		}
 
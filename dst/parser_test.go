@@ -2,6 +2,7 @@ package dst
 
 import (
 	"testing"
+	"testing/fstest"
 
 	"github.com/stretchr/testify/require"
 	"github.com/titpetric/lessgo/internal/strings"
@@ -59,6 +60,20 @@ func TestParserBlockDetection(t *testing.T) {
 				require.Equal(t, []string{".col-@{value}"}, block.SelNames)
 			},
 		},
+		{
+			name:      "single-line each body",
+			input:     `each(@list, { .a-@{value} { x: @index } .b { y: 1 } })`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				each, ok := node.(*Each)
+				require.True(t, ok, "expected Each, got %T", node)
+				require.Equal(t, "@list", each.ListExpr)
+				require.Len(t, each.Children, 2)
+				block, ok := each.Children[1].(*Block)
+				require.True(t, ok, "expected Block child, got %T", each.Children[1])
+				require.Equal(t, []string{".b"}, block.SelNames)
+			},
+		},
 		{
 			name: "function call in declarations",
 			input: `.button {
@@ -150,6 +165,124 @@ func TestParserBlockDetection(t *testing.T) {
 				require.Equal(t, []string{"&.active"}, nestedBlock.SelNames)
 			},
 		},
+		{
+			name: "mixin calls with and without parentheses",
+			input: `.a {
+  .b;
+  .c();
+}`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				block, ok := node.(*Block)
+				require.True(t, ok, "expected Block, got %T", node)
+				require.Equal(t, []Node{
+					&MixinCall{Name: ".b", Args: []string{}, Line: 2},
+					&MixinCall{Name: ".c", Line: 3},
+				}, block.Children)
+			},
+		},
+		{
+			name: "mixin calls with !important",
+			input: `.a {
+  .b !important;
+  .c(1px, red) !important;
+}`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				block, ok := node.(*Block)
+				require.True(t, ok, "expected Block, got %T", node)
+				require.Equal(t, []Node{
+					&MixinCall{Name: ".b", Args: []string{}, Important: true, Line: 2},
+					&MixinCall{Name: ".c", Args: []string{"1px", "red"}, Important: true, Line: 3},
+				}, block.Children)
+			},
+		},
+		{
+			name: "mixin calls with detached ruleset",
+			input: `.a {
+  .b({ color: red; });
+  .c(1px; {
+    width: 1px;
+    .d { color: blue; }
+  });
+}`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				block, ok := node.(*Block)
+				require.True(t, ok, "expected Block, got %T", node)
+				require.Len(t, block.Children, 2)
+
+				b := block.Children[0].(*MixinCall)
+				require.Equal(t, []string{"{}"}, b.Args)
+				require.Len(t, b.Rulesets[0].Children, 1)
+				require.Equal(t, "red", b.Rulesets[0].Children[0].(*Decl).Value)
+
+				c := block.Children[1].(*MixinCall)
+				require.Equal(t, []string{"1px", "{}"}, c.Args)
+				require.Len(t, c.Rulesets[1].Children, 2)
+				require.Equal(t, []string{".d"}, c.Rulesets[1].Children[1].(*Block).SelNames)
+			},
+		},
+		{
+			name: "custom properties with braces",
+			input: `:root {
+  --tokens: { a: b; c: d };
+  --multi: {
+    x: y
+  };
+}`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				block, ok := node.(*Block)
+				require.True(t, ok, "expected Block, got %T", node)
+				require.Len(t, block.Children, 2)
+				require.Equal(t, &Decl{SelNames: []string{}, Key: "--tokens", Value: "{ a: b; c: d }", Line: 2}, block.Children[0])
+				require.Equal(t, &Decl{SelNames: []string{}, Key: "--multi", Value: "{ x: y }", Line: 3}, block.Children[1])
+			},
+		},
+		{
+			name: "detached ruleset with nested rule",
+			input: `@card: {
+  padding: 10px;
+  .title {
+    font-weight: bold;
+  }
+};
+.after { color: red; }`,
+			wantNodes: 2,
+			checkNode: func(t *testing.T, node Node) {
+				blockVar, ok := node.(*BlockVariable)
+				require.True(t, ok, "expected BlockVariable, got %T", node)
+				require.Len(t, blockVar.Children, 2)
+				block, ok := blockVar.Children[1].(*Block)
+				require.True(t, ok, "expected Block, got %T", blockVar.Children[1])
+				require.Equal(t, []string{".title"}, block.SelNames)
+			},
+		},
+		{
+			name:      "namespace at-rule",
+			input:     `@namespace svg url(http://www.w3.org/2000/svg);`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				require.Equal(t, &AtRule{Name: "namespace", Prelude: "svg url(http://www.w3.org/2000/svg)"}, node)
+			},
+		},
+		{
+			name:      "layer order at-rule",
+			input:     `@layer reset, base, components;`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				require.Equal(t, &AtRule{Name: "layer", Prelude: "reset, base, components"}, node)
+			},
+		},
+		{
+			name:      "charset at-rule",
+			input:     `@charset "UTF-8";`,
+			wantNodes: 1,
+			checkNode: func(t *testing.T, node Node) {
+				require.Equal(t, &AtRule{Name: "charset", Prelude: `"UTF-8"`}, node)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -165,3 +298,154 @@ func TestParserBlockDetection(t *testing.T) {
 		})
 	}
 }
+
+func TestParserImportResolution(t *testing.T) {
+	fsys := fstest.MapFS{
+		"theme.less":            {Data: []byte(".theme { a: b; }")},
+		"components/index.less": {Data: []byte(".index { a: b; }")},
+		"buttons/buttons.less":  {Data: []byte(".buttons { a: b; }")},
+		"forms/forms.less":      {Data: []byte(".forms-named { a: b; }")},
+		"forms.less":            {Data: []byte(".forms { a: b; }")},
+		"nested/index.less":     {Data: []byte("@import \"theme\";")},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		selector string
+	}{
+		{"extension", `@import "theme.less";`, ".theme"},
+		{"file without extension", `@import "theme";`, ".theme"},
+		{"directory index", `@import "components";`, ".index"},
+		{"directory named file", `@import "buttons";`, ".buttons"},
+		{"file before directory", `@import "forms";`, ".forms"},
+		{"nested", `@import "nested";`, ".theme"},
+		{"query string", `@import "theme.less?v=2";`, ".theme"},
+		{"fragment", `@import "theme#dark";`, ".theme"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := NewParserWithFS(strings.NewReader(tt.input), fsys).Parse()
+			require.NoError(t, err)
+			require.Len(t, file.Nodes, 1)
+			require.Equal(t, []string{tt.selector}, file.Nodes[0].(*Block).SelNames)
+		})
+	}
+
+	_, err := NewParserWithFS(strings.NewReader(`@import "missing";`), fsys).Parse()
+	require.EqualError(t, err, `import "missing" not found, tried missing.less, missing/index.less, missing/missing.less`)
+
+	_, err = NewParserWithFS(strings.NewReader(`@import "reset.css";`), fsys).Parse()
+	require.NoError(t, err)
+
+	_, err = NewParserWithFS(strings.NewReader(`@import "reset.css?v=2";`), fsys).Parse()
+	require.NoError(t, err)
+
+	file, err := NewParserWithFS(strings.NewReader(`@import "https://example.com/theme.css?v=2#dark";`), fsys).Parse()
+	require.NoError(t, err)
+	require.Equal(t, &Import{Path: "https://example.com/theme.css?v=2#dark"}, file.Nodes[0])
+}
+
+func TestParserImportedComments(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vendor.less": {Data: []byte("/* banner */\n.v {\n  /* inside */\n  a: b;\n}\n")},
+	}
+
+	file, err := NewParserWithFS(strings.NewReader("@import \"vendor\";\n/* entry */\n"), fsys).Parse()
+	require.NoError(t, err)
+	require.Len(t, file.Nodes, 3)
+	require.Equal(t, &Comment{Text: "banner", Multiline: true, Imported: true}, file.Nodes[0])
+	require.Equal(t, &Comment{Text: "inside", Multiline: true, Imported: true}, file.Nodes[1].(*Block).Children[0])
+	require.Equal(t, &Comment{Text: "entry", Multiline: true}, file.Nodes[2])
+}
+
+func TestParserMultilineValues(t *testing.T) {
+	input := ".a {\n  grid-template-areas: \"head head\"\n    \"side main\";\n  font: 12px\n    Arial;\n  color: red\n}\n"
+	file, err := NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+	require.Len(t, file.Nodes, 1)
+
+	var decls []string
+	for _, node := range file.Nodes[0].(*Block).Children {
+		decl := node.(*Decl)
+		decls = append(decls, decl.Key+": "+decl.Value)
+	}
+	require.Equal(t, []string{`grid-template-areas: "head head" "side main"`, "font: 12px Arial", "color: red"}, decls)
+}
+
+func TestParserSourceLines(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vendor.less": {Data: []byte(".v {\n  a: b;\n}\n")},
+	}
+	input := "@import \"vendor\";\r\n/* note\n */\n.a { color: red; .b { top: 0 } }\n.c {\n  font: 12px\n    Arial;\n  margin: /* x */ 0\n}\n"
+	file, err := NewParserWithFS(strings.NewReader(input), fsys).Parse()
+	require.NoError(t, err)
+	require.Len(t, file.Nodes, 4)
+
+	// Imported nodes have no lines of the importing file
+	vendor := file.Nodes[0].(*Block)
+	require.Equal(t, 0, vendor.Line)
+	require.Equal(t, 0, vendor.Children[0].(*Decl).Line)
+
+	a := file.Nodes[2].(*Block)
+	require.Equal(t, 4, a.Line)
+	require.Equal(t, 4, a.Children[0].(*Decl).Line)
+	require.Equal(t, 4, a.Children[1].(*Block).Line)
+
+	c := file.Nodes[3].(*Block)
+	require.Equal(t, 5, c.Line)
+	require.Equal(t, 6, c.Children[0].(*Decl).Line)
+	require.Equal(t, 8, c.Children[1].(*Decl).Line)
+}
+
+func TestParserUnterminated(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"block", ".a {\n  color: red;\n", `unterminated block ".a" starting at line 1`},
+		{"nested block", ".a {\n  .b {\n    color: red;\n  }\n", `unterminated block ".a" starting at line 1`},
+		{"inner block", ".a {\n  .b,\n  .c {\n    color: red;\n}\n", `unterminated block ".a" starting at line 1`},
+		{"media", "@media (min-width: 10px) {\n  .a { color: red; }\n", `unterminated block "@media (min-width: 10px)" starting at line 1`},
+		{"comment", ".a { color: red; }\n/* note\n.b { color: blue; }\n", "unterminated comment starting at line 2"},
+		{"after comment", "/* header */\n.a {\n  color: red;\n", `unterminated block ".a" starting at line 2`},
+		{"valid url", ".a {\n  background: url(http://example.com/a.png);\n}\n", ""},
+		{"valid interpolation", ".a-@{name} {\n  content: \"}\";\n}\n", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewParser(strings.NewReader(tt.input)).Parse()
+			if tt.err == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestNormalizeCommas(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"Arial,sans-serif", "Arial, sans-serif"},
+		{"rgb(1,2,3)", "rgb(1, 2, 3)"},
+		{`"a,b",c`, `"a,b", c`},
+		{"url(data:image/svg+xml;charset=utf8,%3Csvg%3E)", "url(data:image/svg+xml;charset=utf8,%3Csvg%3E)"},
+		{"url(images/a b.png),url(c.png)", "url(images/a b.png), url(c.png)"},
+		{`URL("a,(b).png") no-repeat,red`, `URL("a,(b).png") no-repeat, red`},
+		{"myurl(1,2)", "myurl(1, 2)"},
+		{`"\\",a`, `"\\", a`},
+		{`"\",",a`, `"\",", a`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			require.Equal(t, tt.expected, normalizeCommas(tt.value))
+		})
+	}
+}
@@ -70,6 +70,9 @@ func printNode(node Node, depth int) {
 			printNode(child, depth+1)
 		}
 
+	case *AtRule:
+		fmt.Printf("%sAtRule: @%s %s\n", indent, n.Name, n.Prelude)
+
 	default:
 		fmt.Printf("%s%T\n", indent, node)
 	}
@@ -3,6 +3,7 @@ package dst
 import (
 	"bytes"
 	"io"
+	"slices"
 )
 
 // SanitizeReader wraps an io.Reader and sanitizes minified CSS/LESS input
@@ -19,9 +20,11 @@ func SanitizeReader(r io.Reader) io.Reader {
 }
 
 // SanitizeBytes sanitizes minified CSS/LESS input by injecting newlines
-// after '{', ';', '}' and before '}' characters.
+// after '{', ';', '}' and before '}' characters. A leading UTF-8 BOM is
+// removed and CRLF or CR line endings are converted to LF.
 // Respects quoted strings, comments, and @{...} interpolation blocks.
 func SanitizeBytes(data []byte) []byte {
+	data = normalizeLineEndings(data)
 	if len(data) == 0 {
 		return data
 	}
@@ -33,7 +36,10 @@ func SanitizeBytes(data []byte) []byte {
 	inDoubleQuote := false
 	inSingleLineComment := false
 	inMultiLineComment := false
-	inInterpolation := false
+	interpolationDepth := 0 // nesting depth of @{...} blocks, e.g. @{@{name}}
+
+	// Track parenthesis depth so ';' separated mixin arguments stay on one line
+	parenDepth := 0
 
 	// Track the last meaningful character (outside comments/quotes)
 	// Used to determine if we need to add ';' before '}'
@@ -51,7 +57,7 @@ func SanitizeBytes(data []byte) []byte {
 		}
 
 		// Handle single-line comments
-		if !inSingleQuote && !inDoubleQuote && !inMultiLineComment && !inInterpolation && ch == '/' && nextCh == '/' {
+		if !inSingleQuote && !inDoubleQuote && !inMultiLineComment && interpolationDepth == 0 && ch == '/' && nextCh == '/' {
 			inSingleLineComment = true
 			result = append(result, ch)
 			continue
@@ -65,7 +71,19 @@ func SanitizeBytes(data []byte) []byte {
 		}
 
 		// Handle multi-line comments
-		if !inSingleQuote && !inDoubleQuote && !inSingleLineComment && !inInterpolation && ch == '/' && nextCh == '*' {
+		if !inSingleQuote && !inDoubleQuote && !inSingleLineComment && interpolationDepth == 0 && ch == '/' && nextCh == '*' {
+			// Comments inside a selector or value are dropped, the line-based
+			// parser only understands comments on their own line
+			if end := bytes.Index(data[i+2:], []byte("*/")); end != -1 && isInlinePosition(result) {
+				i += end + 3 // Skip past the closing '*/'
+				// Collapse the whitespace around the removed comment
+				if n := len(result); n > 0 && (result[n-1] == ' ' || result[n-1] == '\t') {
+					for i+1 < len(data) && (data[i+1] == ' ' || data[i+1] == '\t') {
+						i++
+					}
+				}
+				continue
+			}
 			inMultiLineComment = true
 			result = append(result, ch)
 			continue
@@ -86,8 +104,16 @@ func SanitizeBytes(data []byte) []byte {
 			continue
 		}
 
+		// A backslash in a string escapes the next character, so "\\" and
+		// "\"" are closed by their last quote and \2014 passes through as is
+		if (inSingleQuote || inDoubleQuote) && ch == '\\' && nextCh != 0 {
+			result = append(result, ch, nextCh)
+			i++
+			continue
+		}
+
 		// Handle quotes (respecting escapes)
-		if ch == '\'' && prevCh != '\\' && !inDoubleQuote && !inInterpolation {
+		if ch == '\'' && (inSingleQuote || prevCh != '\\') && !inDoubleQuote && interpolationDepth == 0 {
 			inSingleQuote = !inSingleQuote
 			result = append(result, ch)
 			if !inSingleQuote {
@@ -96,7 +122,7 @@ func SanitizeBytes(data []byte) []byte {
 			continue
 		}
 
-		if ch == '"' && prevCh != '\\' && !inSingleQuote && !inInterpolation {
+		if ch == '"' && (inDoubleQuote || prevCh != '\\') && !inSingleQuote && interpolationDepth == 0 {
 			inDoubleQuote = !inDoubleQuote
 			result = append(result, ch)
 			if !inDoubleQuote {
@@ -111,30 +137,68 @@ func SanitizeBytes(data []byte) []byte {
 			continue
 		}
 
-		// Handle @{...} interpolation blocks
+		// Handle @{...} interpolation blocks (which may nest)
 		if ch == '@' && nextCh == '{' {
-			inInterpolation = true
-			result = append(result, ch)
+			interpolationDepth++
+			result = append(result, ch, nextCh)
+			i++ // Skip the '{'
 			continue
 		}
 
 		// End interpolation block
-		if inInterpolation && ch == '}' {
-			inInterpolation = false
+		if interpolationDepth > 0 && ch == '}' {
+			interpolationDepth--
 			result = append(result, ch)
 			lastMeaningfulChar = ch
 			continue
 		}
 
 		// In interpolation, just pass through
-		if inInterpolation {
+		if interpolationDepth > 0 {
 			result = append(result, ch)
 			continue
 		}
 
+		// Custom property values are opaque token streams which may hold braces,
+		// keep them on a single line up to the terminating ';'
+		if ch == '-' && nextCh == '-' && parenDepth == 0 && !isInlinePosition(result) {
+			if end := customPropertyEnd(data, i); end != -1 {
+				// Line breaks in the value collapse into a single space
+				for j := i; j < end; j++ {
+					if data[j] != '\n' && data[j] != '\r' {
+						result = append(result, data[j])
+						continue
+					}
+					result = append(trimTrailingWhitespace(result), ' ')
+					for j+1 < end && (data[j+1] == ' ' || data[j+1] == '\t' || data[j+1] == '\n' || data[j+1] == '\r') {
+						j++
+					}
+				}
+				lastMeaningfulChar = data[end-1]
+				i = end - 1
+				if lastMeaningfulChar == ';' && end < len(data) && data[end] != '\n' && data[end] != '\r' {
+					result = append(result, '\n')
+				}
+				continue
+			}
+		}
+
 		// Handle structural characters outside quotes/comments/interpolation
 		switch ch {
+		case '(':
+			parenDepth++
+			result = append(result, ch)
+			lastMeaningfulChar = ch
+
+		case ')':
+			if parenDepth > 0 {
+				parenDepth--
+			}
+			result = append(result, ch)
+			lastMeaningfulChar = ch
+
 		case '{':
+			parenDepth = 0
 			result = append(result, ch)
 			lastMeaningfulChar = ch
 			// Add newline after '{' if not already followed by newline
@@ -145,12 +209,17 @@ func SanitizeBytes(data []byte) []byte {
 		case ';':
 			result = append(result, ch)
 			lastMeaningfulChar = ch
+			// Semicolons inside parentheses separate mixin arguments
+			if parenDepth > 0 {
+				continue
+			}
 			// Add newline after ';' if not already followed by newline
 			if nextCh != '\n' && nextCh != '\r' {
 				result = append(result, '\n')
 			}
 
 		case '}':
+			parenDepth = 0
 			// Trim trailing whitespace before '}'
 			result = trimTrailingWhitespace(result)
 			// Add ';' before '}' if the last statement doesn't have one
@@ -170,6 +239,26 @@ func SanitizeBytes(data []byte) []byte {
 				result = append(result, '\n')
 			}
 
+		case '\n':
+			line, rest := currentLine(result), data[i+1:]
+			switch {
+			case continuesValue(line, rest, parenDepth > 0):
+				// Join a value spanning several lines into its declaration
+				result = trimTrailingWhitespace(result)
+				for i+1 < len(data) && isSpaceByte(data[i+1]) {
+					i++
+				}
+				if n := len(result); n > 0 && result[n-1] != '(' && i+1 < len(data) && bytes.IndexByte([]byte(",;)"), data[i+1]) == -1 {
+					result = append(result, ' ')
+				}
+			case parenDepth == 0 && missingSemicolon(line, rest):
+				// Repair a declaration or mixin call missing its trailing ';'
+				result = append(result, ';', ch)
+				lastMeaningfulChar = ';'
+			default:
+				result = append(result, ch)
+			}
+
 		default:
 			// Track non-whitespace characters
 			if ch != ' ' && ch != '\t' && ch != '\n' && ch != '\r' {
@@ -182,6 +271,295 @@ func SanitizeBytes(data []byte) []byte {
 	return result
 }
 
+// customPropertyEnd returns the end of a custom property declaration
+// ("--name: value;") starting at i, including the ';'. A value may contain
+// balanced braces. It returns -1 if there is no custom property at i.
+func customPropertyEnd(data []byte, i int) int {
+	j := i + 2
+	for j < len(data) {
+		if isNameChar(data[j]) {
+			j++
+			continue
+		}
+		// Interpolated name part, e.g. --@{name}-color
+		if data[j] == '@' && j+1 < len(data) && data[j+1] == '{' {
+			k := bytes.IndexByte(data[j:], '}')
+			if k == -1 {
+				return -1
+			}
+			j += k + 1
+			continue
+		}
+		break
+	}
+	for j < len(data) && (data[j] == ' ' || data[j] == '\t') {
+		j++
+	}
+	if j >= len(data) || data[j] != ':' {
+		return -1
+	}
+
+	depth := 0
+	var quote byte
+	for j++; j < len(data); j++ {
+		c := data[j]
+		switch {
+		case quote != 0:
+			if c == quote && data[j-1] != '\\' {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '{' || c == '(' || c == '[':
+			depth++
+		case c == '}' && depth == 0:
+			// The enclosing block ends, the ';' is optional
+			return trimTrailingSpace(data, i, j)
+		case c == '}' || c == ')' || c == ']':
+			depth--
+		case c == ';' && depth == 0:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// trimTrailingSpace returns end moved back over whitespace, but not before start
+func trimTrailingSpace(data []byte, start, end int) int {
+	for end > start && (data[end-1] == ' ' || data[end-1] == '\t' || data[end-1] == '\n' || data[end-1] == '\r') {
+		end--
+	}
+	return end
+}
+
+// isNameChar reports whether c can be part of a property name
+func isNameChar(c byte) bool {
+	return c == '-' || c == '_' || c >= '0' && c <= '9' || c|0x20 >= 'a' && c|0x20 <= 'z'
+}
+
+// currentLine returns the content written since the last newline
+func currentLine(data []byte) []byte {
+	if i := bytes.LastIndexByte(data, '\n'); i != -1 {
+		return data[i+1:]
+	}
+	return data
+}
+
+// missingSemicolon reports whether line is a declaration or mixin call that
+// isn't terminated by ';', judging by the line and the input that follows.
+// Lines continued on the next line (open brace, trailing comma or operator)
+// are left alone.
+func missingSemicolon(line, rest []byte) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 {
+		return false
+	}
+
+	switch line[len(line)-1] {
+	case ';', '{', '}', ',', '(', ':', '>', '+', '~', '*', '/':
+		return false
+	}
+
+	// A selector followed by '{' on the next line
+	rest = bytes.TrimLeft(rest, " \t\r\n")
+	if len(rest) == 0 || rest[0] == '{' {
+		return false
+	}
+
+	switch first := line[0]; {
+	case first == '.' || first == '#':
+		// Mixin call: .mixin() or #ns.mixin()
+		return line[len(line)-1] == ')'
+	default:
+		return isDeclaration(line) && statementFollows(rest)
+	}
+}
+
+// continuesValue reports whether the line after line continues the value of
+// the declaration on it, e.g. grid-template-areas listing one row per line.
+// Within parentheses a declaration always continues.
+func continuesValue(line, rest []byte, inParens bool) bool {
+	line = bytes.TrimSpace(line)
+	if len(line) == 0 || !isDeclaration(line) {
+		return false
+	}
+	switch line[len(line)-1] {
+	case ';', '{', '}':
+		return false
+	}
+	if inParens {
+		return true
+	}
+	return !statementFollows(bytes.TrimLeft(rest, " \t\r\n"))
+}
+
+// isDeclaration reports whether line starts a property or variable
+// declaration. The name before ':' is a single word, which rules out at-rule
+// preludes like "@media (a: b) and".
+func isDeclaration(line []byte) bool {
+	if len(line) == 0 {
+		return false
+	}
+	switch first := line[0]; {
+	case first == '@' || first == '-' || first == '*' || first == '_' || (first|0x20 >= 'a' && first|0x20 <= 'z'):
+		colon := bytes.IndexByte(line, ':')
+		return colon > 0 && !bytes.ContainsAny(bytes.TrimRight(line[:colon], " \t"), " \t(")
+	}
+	return false
+}
+
+// statementFollows reports whether rest starts a new statement rather than
+// continuing the value on the line before it. A block opening before the
+// next ';' means a selector follows, otherwise the next line has to start
+// like a declaration, mixin call, comment or statement at-rule.
+func statementFollows(rest []byte) bool {
+	if len(rest) == 0 || rest[0] == '}' || bytes.HasPrefix(rest, []byte("//")) || bytes.HasPrefix(rest, []byte("/*")) {
+		return true
+	}
+
+	depth := 0
+	var quote byte
+scan:
+	for i, c := range rest {
+		switch {
+		case quote != 0:
+			if c == quote && rest[i-1] != '\\' {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth <= 0 && c == '{' && (i == 0 || rest[i-1] != '@'):
+			return true
+		case depth <= 0 && (c == ';' || c == '}'):
+			break scan
+		}
+	}
+
+	next := rest
+	if i := bytes.IndexByte(next, '\n'); i != -1 {
+		next = next[:i]
+	}
+	next = bytes.TrimSpace(next)
+	if isDeclaration(next) {
+		return true
+	}
+
+	second := byte(0)
+	if len(next) > 1 {
+		second = next[1]
+	}
+	switch next[0] {
+	case '&':
+		return true
+	case '.':
+		// Mixin call, unlike a number like .5em
+		return second == '-' || second == '_' || second|0x20 >= 'a' && second|0x20 <= 'z'
+	case '#':
+		// Namespaced mixin call, unlike a color like #fff
+		return bytes.IndexByte(next, '(') != -1
+	case '@':
+		// Detached ruleset call or statement at-rule, unlike a variable
+		end := 1
+		for end < len(next) && isNameChar(next[end]) {
+			end++
+		}
+		if end < len(next) && next[end] == '(' {
+			return true
+		}
+		name := string(next[1:end])
+		return name == "import" || name == "plugin" || slices.Contains(statementAtRules, name)
+	}
+	return false
+}
+
+// isInlinePosition reports whether the current output line already has content,
+// meaning a comment starting here sits inside a selector or a value.
+func isInlinePosition(data []byte) bool {
+	for i := len(data) - 1; i >= 0; i-- {
+		switch data[i] {
+		case '\n', '\r':
+			return false
+		case ' ', '\t':
+			continue
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+// utf8BOM is the byte order mark some editors write at the start of a file
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// normalizeLineEndings strips a UTF-8 BOM and converts CRLF and lone CR line
+// endings to LF, so files saved on Windows (or with mixed endings) parse the same.
+func normalizeLineEndings(data []byte) []byte {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if bytes.IndexByte(data, '\r') == -1 {
+		return data
+	}
+
+	result := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] != '\r' {
+			result = append(result, data[i])
+			continue
+		}
+		result = append(result, '\n')
+		if i+1 < len(data) && data[i+1] == '\n' {
+			i++
+		}
+	}
+	return result
+}
+
+// sourceLines maps the lines of sanitized, the output of SanitizeBytes for
+// data, to the 1-based lines of data they start on. Characters are matched
+// in order, skipping whitespace, the semicolons the sanitizer inserts and
+// the inline comments it removes. Lines without content map to 0.
+func sourceLines(data, sanitized []byte) []int {
+	data = normalizeLineEndings(data)
+	lines := []int{0}
+	line, i := 1, 0
+	for _, ch := range sanitized {
+		if ch == '\n' {
+			lines = append(lines, 0)
+			continue
+		}
+		if isSpaceByte(ch) {
+			continue
+		}
+		for {
+			for i < len(data) && isSpaceByte(data[i]) {
+				if data[i] == '\n' {
+					line++
+				}
+				i++
+			}
+			if i+1 >= len(data) || data[i] == ch || data[i] != '/' || data[i+1] != '*' {
+				break
+			}
+			end := bytes.Index(data[i+2:], []byte("*/"))
+			if end == -1 {
+				break
+			}
+			line += bytes.Count(data[i:i+end+4], []byte("\n"))
+			i += end + 4
+		}
+		if lines[len(lines)-1] == 0 {
+			lines[len(lines)-1] = line
+		}
+		if i < len(data) && data[i] == ch {
+			i++
+		}
+	}
+	return lines
+}
+
 // trimTrailingWhitespace removes trailing spaces and tabs from the slice.
 // Does not remove newlines since those are structural.
 func trimTrailingWhitespace(data []byte) []byte {
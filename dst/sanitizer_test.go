@@ -31,6 +31,16 @@ func TestSanitizeBytes(t *testing.T) {
 			// Content inside quotes is preserved
 			expected: ".foo {\n content: \"{ ; }\";\n}",
 		},
+		{
+			name:     "escaped backslash closes the string",
+			input:    `.foo { content: "\\"; a: b; }`,
+			expected: ".foo {\n content: \"\\\\\";\n a: b;\n}",
+		},
+		{
+			name:     "escaped quote and unicode escape",
+			input:    `.foo { content: "\"\2014"; a: '\''; }`,
+			expected: ".foo {\n content: \"\\\"\\2014\";\n a: '\\'';\n}",
+		},
 		{
 			name:  "declaration without trailing semicolon",
 			input: `.foo { color: red }`,
@@ -43,12 +53,80 @@ func TestSanitizeBytes(t *testing.T) {
 			// @{...} interpolation blocks are not broken by newlines
 			expected: ".@{prefix} {\n color: red;\n}",
 		},
+		{
+			name:  "nested interpolation preserved",
+			input: `.a { @{@{ptr}}: red; }`,
+			// Nested @{...} blocks are tracked by depth
+			expected: ".a {\n @{@{ptr}}: red;\n}",
+		},
 		{
 			name:  "comment only block",
 			input: ".foo { // comment\n}",
 			// No semicolon added when block only contains comments
 			expected: ".foo {\n // comment\n}",
 		},
+		{
+			name:  "semicolons in mixin arguments",
+			input: `.a { .m(dark; #000); }`,
+			// Semicolons inside parentheses separate arguments, not statements
+			expected: ".a {\n .m(dark; #000);\n}",
+		},
+		{
+			name:  "comment inside selector",
+			input: ".a /* note */ .b {\n  color: red;\n}",
+			// Inline comments are dropped, surrounding whitespace collapsed
+			expected: ".a .b {\n  color: red;\n}",
+		},
+		{
+			name:     "comment inside value",
+			input:    ".a {\n  width: /* x */ 10px;\n}",
+			expected: ".a {\n  width: 10px;\n}",
+		},
+		{
+			name:  "missing semicolon repaired",
+			input: ".a {\n  color: red\n  margin: 0;\n  .m()\n}",
+			// Unterminated declarations and mixin calls get their ';'
+			expected: ".a {\n  color: red;\n  margin: 0;\n  .m();\n}",
+		},
+		{
+			name:  "selector on its own line untouched",
+			input: ".a,\n.b:hover\n{\n  color: red;\n}",
+			// A line followed by '{' is a selector, not a declaration
+			expected: ".a,\n.b:hover\n{\n  color: red;\n}",
+		},
+		{
+			name:  "multi-line value joined",
+			input: ".a {\n  grid-template-areas:\n    \"a b\"\n    \"c d\";\n}",
+			// Continuation lines belong to the value, they don't need a ';'
+			expected: ".a {\n  grid-template-areas: \"a b\" \"c d\";\n}",
+		},
+		{
+			name:     "multi-line value after first row joined",
+			input:    ".a {\n  grid-template-areas: \"head head\"\n    \"side main\";\n  font: 12px\n    Arial;\n  color: red\n}",
+			expected: ".a {\n  grid-template-areas: \"head head\" \"side main\";\n  font: 12px Arial;\n  color: red;\n}",
+		},
+		{
+			name:     "multi-line function arguments joined",
+			input:    ".a {\n  background: linear-gradient(\n    red,\n    blue\n  );\n  transition:\n    color 1s,\n    width 2s;\n}",
+			expected: ".a {\n  background: linear-gradient(red, blue);\n  transition: color 1s, width 2s;\n}",
+		},
+		{
+			name:     "missing semicolon before statements repaired",
+			input:    ".a {\n  margin: @a\n    @b;\n  color: red\n  .m();\n  width: 1px\n  &:hover {\n    color: blue;\n  }\n  height: 1px\n  @import \"x\";\n}",
+			expected: ".a {\n  margin: @a @b;\n  color: red;\n  .m();\n  width: 1px;\n  &:hover {\n    color: blue;\n}\n  height: 1px;\n  @import \"x\";\n}",
+		},
+		{
+			name:  "BOM and CRLF line endings",
+			input: "\xef\xbb\xbf@import \"a.less\";\r\n.a {\r\n  color: red\r\n}\r",
+			// The BOM is stripped and line endings normalized to LF
+			expected: "@import \"a.less\";\n.a {\n  color: red;\n}\n",
+		},
+		{
+			name:  "standalone comment preserved",
+			input: ".a {\n  /* note */\n  color: red;\n}",
+			// Comments on their own line are kept for the parser
+			expected: ".a {\n  /* note */\n  color: red;\n}",
+		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,103 @@
+package dst
+
+import (
+	"fmt"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// openBlock is a '{' that hasn't been closed yet
+type openBlock struct {
+	name string // the selector or at-rule before the brace
+	line int    // 1-based line of the opening brace
+}
+
+// checkUnterminated reports the first block or comment in data that isn't
+// closed before EOF, naming the line it starts on. Quoted strings, comments
+// and url(...) contents are skipped.
+func checkUnterminated(data []byte) error {
+	var stack []openBlock
+	line := 1
+	parenDepth := 0
+	interpolationDepth := 0
+	statementStart := 0
+
+	for i := 0; i < len(data); i++ {
+		ch := data[i]
+		next := byte(0)
+		if i+1 < len(data) {
+			next = data[i+1]
+		}
+
+		switch {
+		case ch == '\n':
+			line++
+
+		case ch == '/' && next == '*':
+			start := line
+			end := strings.Index(string(data[i+2:]), "*/")
+			if end == -1 {
+				return fmt.Errorf("unterminated comment starting at line %d", start)
+			}
+			line += strings.Count(string(data[i:i+2+end]), "\n")
+			i += end + 3
+			statementStart = i + 1
+
+		case ch == '/' && next == '/' && parenDepth == 0:
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			statementStart = i
+			i-- // let the newline be counted
+
+		case ch == '"' || ch == '\'':
+			for i++; i < len(data) && data[i] != ch && data[i] != '\n'; i++ {
+				if data[i] == '\\' {
+					i++
+				}
+			}
+			if i < len(data) && data[i] == '\n' {
+				line++
+			}
+
+		case ch == '(':
+			parenDepth++
+
+		case ch == ')':
+			if parenDepth > 0 {
+				parenDepth--
+			}
+
+		case ch == '{' && i > 0 && data[i-1] == '@':
+			interpolationDepth++
+
+		case ch == '}' && interpolationDepth > 0:
+			interpolationDepth--
+
+		case ch == '{':
+			name := strings.Join(strings.Fields(string(data[statementStart:i])), " ")
+			stack = append(stack, openBlock{name: name, line: line})
+			statementStart = i + 1
+
+		case ch == '}':
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			statementStart = i + 1
+
+		case ch == ';':
+			if parenDepth == 0 {
+				statementStart = i + 1
+			}
+		}
+	}
+
+	if len(stack) > 0 {
+		open := stack[len(stack)-1]
+		if open.name == "" {
+			return fmt.Errorf("unterminated block starting at line %d", open.line)
+		}
+		return fmt.Errorf("unterminated block %q starting at line %d", open.name, open.line)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package dst
+
+// Walk traverses the tree rooted at node depth-first, calling fn for each
+// node before its children. If fn returns false, the children are skipped.
+// Nodes are pointers, so fn may modify them in place to transform the tree.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+	for _, child := range Children(node) {
+		Walk(child, fn)
+	}
+}
+
+// WalkFile calls Walk for each top-level node of the file
+func WalkFile(file *File, fn func(Node) bool) {
+	for _, node := range file.Nodes {
+		Walk(node, fn)
+	}
+}
+
+// Children returns the nested nodes of a Block, BlockVariable or Each
+func Children(node Node) []Node {
+	switch n := node.(type) {
+	case *Block:
+		return n.Children
+	case *BlockVariable:
+		return n.Children
+	case *Each:
+		return n.Children
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package dst
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+func TestWalk(t *testing.T) {
+	input := `@color: red;
+.a {
+  color: @color;
+  .b {
+    background: @color;
+  }
+}
+.c {
+  margin: 0;
+}`
+	file, err := NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	// Collect node types in traversal order
+	var types []NodeType
+	WalkFile(file, func(node Node) bool {
+		types = append(types, node.Type())
+		return true
+	})
+	require.Equal(t, []NodeType{TypeDecl, TypeBlock, TypeDecl, TypeBlock, TypeDecl, TypeBlock, TypeDecl}, types)
+
+	// Returning false skips the children
+	var blocks []string
+	WalkFile(file, func(node Node) bool {
+		if block, ok := node.(*Block); ok {
+			blocks = append(blocks, block.SelNames...)
+			return false
+		}
+		return true
+	})
+	require.Equal(t, []string{".a", ".c"}, blocks)
+
+	// Rename a variable in place
+	WalkFile(file, func(node Node) bool {
+		if decl, ok := node.(*Decl); ok {
+			decl.Key = strings.ReplaceAll(decl.Key, "@color", "@primary")
+			decl.Value = strings.ReplaceAll(decl.Value, "@color", "@primary")
+		}
+		return true
+	})
+	require.Equal(t, "@primary: red;\n.a {\n  color: @primary;\n  .b {\n    background: @primary;\n  }\n}\n\n.c {\n  margin: 0;\n}\n\n", NewFormatter().Format(file))
+}
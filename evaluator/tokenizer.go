@@ -80,9 +80,9 @@ func Tokenize(input string) ([]Token, error) {
 		}
 
 		if r == '(' {
-			// Only treat as function call if there was a preceding identifier token
-			// Otherwise it's grouping parentheses
-			if len(tokens) > 0 && tokens[len(tokens)-1].Type == TokenIdent {
+			// Only treat as function call if there was a preceding identifier token,
+			// or a name directly before it like scale(2). Otherwise it's grouping parentheses
+			if last := len(tokens) - 1; last >= 0 && (tokens[last].Type == TokenIdent || tokens[last].Type == TokenValue && !space) {
 				open = true
 				current = string(r)
 				i++
@@ -98,12 +98,37 @@ func Tokenize(input string) ([]Token, error) {
 			continue
 		}
 
+		// A sign directly before a number is part of it, e.g. "0 -10px" is a list
+		if (r == '-' || r == '+') && isSign(runes, i) {
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.' || runes[i] == '%') {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenValue, Text: string(runes[start:i])})
+			space = false
+			continue
+		}
+
+		// Inequality, e.g. @mode != dark
+		if space && r == '!' && i+1 < len(runes) && runes[i+1] == '=' {
+			tokens = append(tokens, Token{Type: TokenOp, Text: "!="})
+			space = false
+			i += 2
+			continue
+		}
+
 		// operators: = > <
 		if space && (r == '=' || r == '>' || r == '<' || r == '*' || r == '+' || r == '-' || r == '/') {
 			body := string(r)
 			if r == '*' {
 				//body = "\\*"
 			}
+			// Two-character comparison operators: <=, >=, =<, =>
+			if i+1 < len(runes) && ((r == '<' || r == '>') && runes[i+1] == '=' || r == '=' && (runes[i+1] == '<' || runes[i+1] == '>')) {
+				body += string(runes[i+1])
+				i++
+			}
 			tokens = append(tokens, Token{Type: TokenOp, Text: body})
 			space = false
 			i++
@@ -122,6 +147,25 @@ func Tokenize(input string) ([]Token, error) {
 			continue
 		}
 
+		// quoted strings ("dark mode") keep their quotes and inner spaces
+		if r == '"' || r == '\'' {
+			start := i
+			i++
+			for i < len(runes) && runes[i] != r {
+				if runes[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(runes) {
+				// Unterminated, keep the input whole like other unknown values
+				return []Token{{Type: TokenValue, Text: input}}, nil
+			}
+			i++
+			tokens = append(tokens, Token{Type: TokenValue, Text: string(runes[start:i])})
+			continue
+		}
+
 		// bare values (dark)
 		if unicode.IsLetter(r) {
 			start := i
@@ -133,10 +177,22 @@ func Tokenize(input string) ([]Token, error) {
 			continue
 		}
 
+		// hex colors (#fff)
+		if r == '#' {
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			tokens = append(tokens, Token{Type: TokenValue, Text: string(runes[start:i])})
+			continue
+		}
+
+		// numbers with their unit (50%, 1.5em)
 		if unicode.IsDigit(r) {
 			start := i
 			i++
-			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i])) {
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || unicode.IsLetter(runes[i]) || runes[i] == '.' || runes[i] == '%') {
 				i++
 			}
 			tokens = append(tokens, Token{Type: TokenValue, Text: string(runes[start:i])})
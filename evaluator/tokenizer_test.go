@@ -19,3 +19,65 @@ func TestTokenizer(t *testing.T) {
 	require.NotEmpty(t, tok)
 	require.NoError(t, err)
 }
+
+func TestTokenizerComparison(t *testing.T) {
+	tok, err := Tokenize("@v <= 0")
+	require.NoError(t, err)
+	require.Len(t, tok, 3)
+	require.Equal(t, Token{Type: TokenOp, Text: "<="}, tok[1])
+
+	tok, err = Tokenize("@v >= 10px")
+	require.NoError(t, err)
+	require.Equal(t, Token{Type: TokenOp, Text: ">="}, tok[1])
+
+	tok, err = Tokenize("@w > 50%")
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenIdent, Text: "@w"}, {Type: TokenOp, Text: ">"}, {Type: TokenValue, Text: "50%"}}, tok)
+
+	tok, err = Tokenize("@size < 1.5em")
+	require.NoError(t, err)
+	require.Equal(t, Token{Type: TokenValue, Text: "1.5em"}, tok[2])
+
+	tok, err = Tokenize("@c = #FFF")
+	require.NoError(t, err)
+	require.Equal(t, Token{Type: TokenValue, Text: "#FFF"}, tok[2])
+}
+
+func TestTokenizerNegativeNumbers(t *testing.T) {
+	tok, err := Tokenize("0 -10px")
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenValue, Text: "0"}, {Type: TokenValue, Text: "-10px"}}, tok)
+	require.False(t, IsExpression(tok))
+
+	tok, err = Tokenize("-10px + 5px")
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenValue, Text: "-10px"}, {Type: TokenOp, Text: "+"}, {Type: TokenValue, Text: "5px"}}, tok)
+
+	tok, err = Tokenize("10px * -1")
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenValue, Text: "10px"}, {Type: TokenOp, Text: "*"}, {Type: TokenValue, Text: "-1"}}, tok)
+
+	tok, err = Tokenize("@x - 15px")
+	require.NoError(t, err)
+	require.Equal(t, Token{Type: TokenOp, Text: "-"}, tok[1])
+}
+
+func TestTokenizerFunctionCalls(t *testing.T) {
+	tok, err := Tokenize("rotate(10deg) scale(2)")
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenValue, Text: "rotate(10deg)"}, {Type: TokenValue, Text: "scale(2)"}}, tok)
+}
+
+func TestTokenizerStrings(t *testing.T) {
+	tok, err := Tokenize(`@mode = "dark mode"`)
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenIdent, Text: "@mode"}, {Type: TokenOp, Text: "="}, {Type: TokenValue, Text: `"dark mode"`}}, tok)
+
+	tok, err = Tokenize(`@mode != 'light'`)
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenIdent, Text: "@mode"}, {Type: TokenOp, Text: "!="}, {Type: TokenValue, Text: `'light'`}}, tok)
+
+	tok, err = Tokenize(`@mode = "dark`)
+	require.NoError(t, err)
+	require.Equal(t, []Token{{Type: TokenValue, Text: `@mode = "dark`}}, tok)
+}
@@ -1,5 +1,7 @@
 package evaluator
 
+import "unicode"
+
 // isValueChar checks if a character can be part of a value
 func isValueChar(r rune) bool {
 	return (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r == '%' || r == '#'
@@ -9,3 +11,12 @@ func isValueChar(r rune) bool {
 func isDigit(r byte) bool {
 	return r >= '0' && r <= '9'
 }
+
+// isSign checks if the +/- at runes[i] is the sign of a number: it is
+// directly followed by a digit and not preceded by an operand without space
+func isSign(runes []rune, i int) bool {
+	if i+1 >= len(runes) || !(unicode.IsDigit(runes[i+1]) || runes[i+1] == '.') {
+		return false
+	}
+	return i == 0 || unicode.IsSpace(runes[i-1])
+}
@@ -0,0 +1,60 @@
+package expression
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/expression/functions"
+)
+
+// TestColorMatchesFunctions cross-checks the evaluator colors against the function layer
+func TestColorMatchesFunctions(t *testing.T) {
+	inputs := []string{
+		"#333", "#336699", "#33669980", "#fff8", "red", "rebeccapurple",
+		"rgb(51, 102, 153)", "rgba(51, 102, 153, 0.5)", "rgb(51 102 153 / 50%)",
+		"hsl(210, 50%, 40%)", "hsla(210, 50%, 40%, 0.25)",
+	}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			want, err := functions.ParseColor(input)
+			require.NoError(t, err)
+			v, err := Parse(input)
+			require.NoError(t, err)
+			require.Equal(t, want, v.Color)
+
+			// Color functions give the same results in expressions
+			eval, err := NewEvaluator(map[string]string{"c": want.ToHex()})
+			require.NoError(t, err)
+			got, err := eval.Eval("lighten(@c, 10%)")
+			require.NoError(t, err)
+			require.Equal(t, functions.Lighten(want.ToHex(), "10%"), got.String())
+		})
+	}
+
+	for _, input := range []string{"#ggg", "#12345", "bold"} {
+		_, err := functions.ParseColor(input)
+		require.Error(t, err, input)
+		_, err = ParseColor(input)
+		require.Error(t, err, input)
+	}
+}
+
+func TestColorString(t *testing.T) {
+	tests := map[string]string{
+		"#333":                     "#333",
+		"#80ff8000":                "#80ff8000",
+		"rgb(51, 102, 153)":        "#336699",
+		"rgba(51, 102, 153, 0.5)":  "rgba(51, 102, 153, 0.5)",
+		"hsl(210, 50%, 40%)":       "hsl(210, 50%, 40%)",
+		"hsla(210, 50%, 40%, 0.5)": "hsla(210, 50%, 40%, 0.5)",
+		"red":                      "red",
+	}
+
+	for input, want := range tests {
+		v, err := Parse(input)
+		require.NoError(t, err)
+		require.NotNil(t, v.Color, input)
+		require.Equal(t, want, v.String(), input)
+	}
+}
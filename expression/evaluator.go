@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 
+	"github.com/titpetric/lessgo/expression/functions"
 	"github.com/titpetric/lessgo/internal/strings"
 )
 
@@ -14,26 +15,60 @@ var (
 
 // Evaluator evaluates LESS expressions
 type Evaluator struct {
+	raw       map[string]string // variable sources, parsed on first use
 	variables map[string]*Value
+	ctx       *functions.Context
 }
 
 // NewEvaluator creates a new evaluator
 func NewEvaluator(vars map[string]string) (*Evaluator, error) {
-	result := &Evaluator{
+	return NewEvaluatorWithContext(vars, nil)
+}
+
+// NewEvaluatorWithContext creates a new evaluator that evaluates functions
+// and formats values with the settings of ctx
+func NewEvaluatorWithContext(vars map[string]string, ctx *functions.Context) (*Evaluator, error) {
+	return &Evaluator{
+		raw:       vars,
 		variables: make(map[string]*Value),
+		ctx:       ctx,
+	}, nil
+}
+
+// parse parses s into a Value using the settings of the evaluator
+func (e *Evaluator) parse(s string) (*Value, error) {
+	v, err := Parse(s)
+	if err != nil {
+		return nil, err
 	}
-	for k, v := range vars {
-		r, err := Parse(v)
-		if err != nil {
-			return nil, err
-		}
-		result.variables[k] = r
+	v.ctx = e.ctx
+	if v.Color != nil {
+		v.Raw = v.String()
+	}
+	return v, nil
+}
+
+// variable returns the parsed value of a variable. Values are parsed lazily,
+// as scopes in recursive mixins hold many variables an expression never uses.
+func (e *Evaluator) variable(name string) (*Value, bool, error) {
+	if v, ok := e.variables[name]; ok {
+		return v, true, nil
+	}
+	raw, ok := e.raw[name]
+	if !ok {
+		return nil, false, nil
+	}
+	v, err := e.parse(raw)
+	if err != nil {
+		return nil, false, fmt.Errorf("variable @%s: %w", name, err)
 	}
-	return result, nil
+	e.variables[name] = v
+	return v, true, nil
 }
 
 // SetVariable sets a variable value
 func (e *Evaluator) SetVariable(name string, v *Value) {
+	v.ctx = e.ctx
 	e.variables[name] = v
 }
 
@@ -50,10 +85,15 @@ func (e *Evaluator) Eval(expr string) (*Value, error) {
 }
 
 // substituteVariables replaces @variable with their values
-func (e *Evaluator) substituteVariables(expr string) string {
-	return varSubstituteRegex.ReplaceAllStringFunc(expr, func(match string) string {
+func (e *Evaluator) substituteVariables(expr string) (string, error) {
+	var firstErr error
+	result := varSubstituteRegex.ReplaceAllStringFunc(expr, func(match string) string {
 		varName := match[1:] // remove the @
-		if v, ok := e.variables[varName]; ok {
+		v, ok, err := e.variable(varName)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if ok {
 			// Prefer Raw field for lists and other non-numeric values
 			if v.Raw != "" {
 				return v.Raw
@@ -62,6 +102,7 @@ func (e *Evaluator) substituteVariables(expr string) string {
 		}
 		return match
 	})
+	return result, firstErr
 }
 
 // evalExpression evaluates a mathematical expression
@@ -73,7 +114,11 @@ func (e *Evaluator) evalExpression(expr string) (*Value, error) {
 		// Check for variable reference
 		if strings.HasPrefix(expr, "@") && !strings.ContainsAny(expr, " ()+-*/") {
 			varName := strings.TrimPrefix(expr, "@")
-			if v, ok := e.variables[varName]; ok {
+			v, ok, err := e.variable(varName)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
 				return v, nil
 			}
 		}
@@ -84,7 +129,7 @@ func (e *Evaluator) evalExpression(expr string) (*Value, error) {
 		}
 
 		// Parse as a value
-		v, err := Parse(expr)
+		v, err := e.parse(expr)
 		if err != nil {
 			return nil, err
 		}
@@ -95,7 +140,7 @@ func (e *Evaluator) evalExpression(expr string) (*Value, error) {
 			result := e.evaluateEmbeddedFunctions(v.Raw)
 			if result != v.Raw {
 				// Successfully evaluated functions, return new value
-				return Parse(result)
+				return e.parse(result)
 			}
 		}
 
@@ -118,19 +163,36 @@ func (e *Evaluator) evalFunctionCall(expr string) (*Value, error) {
 		// Reconstruct the function call with substituted variables
 		var argStrs []string
 		for _, v := range args {
-			argStr := v.String()
-			argStr = e.substituteVariables(argStr)
+			argStr, err := e.substituteVariables(v.String())
+			if err != nil {
+				return nil, err
+			}
 			argStrs = append(argStrs, argStr)
 		}
 		result := funcName + "(" + strings.Join(argStrs, ", ") + ")"
-		return Parse(result) // Return as raw string
+		return e.parse(result) // Return as raw string
 	}
 
 	funcArgs := make([]any, 0, len(args))
 	for _, v := range args {
-		argStr := v.String()
 		// Substitute variables in function arguments
-		argStr = e.substituteVariables(argStr)
+		argStr, err := e.substituteVariables(v.String())
+		if err != nil {
+			return nil, err
+		}
+		// Evaluate nested function calls, e.g. unit(percentage(0.5))
+		if IsFunctionCall(argStr) && strings.HasSuffix(argStr, ")") {
+			if nested, err := e.Eval(argStr); err == nil {
+				argStr = nested.String()
+			}
+		} else if isParenthesized(argStr) {
+			// Evaluate parenthesized math, e.g. percentage((1 / 4))
+			if nested, err := e.Eval(argStr[1 : len(argStr)-1]); err == nil {
+				if result := nested.String(); nested.Color != nil || numericUnitRegex.MatchString(result) {
+					argStr = result
+				}
+			}
+		}
 		funcArgs = append(funcArgs, argStr)
 	}
 
@@ -138,18 +200,41 @@ func (e *Evaluator) evalFunctionCall(expr string) (*Value, error) {
 		return nil, fmt.Errorf("Unknown function: %s", funcName)
 	}
 
-	res, err := Call(funcName, funcArgs...)
+	res, err := CallWithContext(e.ctx, funcName, funcArgs...)
 	if err != nil {
 		return nil, err
 	}
 
-	return Parse(fmt.Sprint(res))
+	return e.parse(fmt.Sprint(res))
+}
+
+// isParenthesized reports whether expr is wrapped in a single pair of parentheses
+func isParenthesized(expr string) bool {
+	if !strings.HasPrefix(expr, "(") || !strings.HasSuffix(expr, ")") {
+		return false
+	}
+	depth := 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i < len(expr)-1 {
+				return false
+			}
+		}
+	}
+	return depth == 0
 }
 
 // parseAddSub handles + and - operators
 func (e *Evaluator) parseAddSub(expr string) (*Value, error) {
 	// Substitute variables in arithmetic expressions
-	expr = e.substituteVariables(expr)
+	expr, err := e.substituteVariables(expr)
+	if err != nil {
+		return nil, err
+	}
 
 	// Find operators not inside parentheses
 	parts := splitByOperator(expr, []string{"+", "-"})
@@ -191,7 +276,7 @@ func (e *Evaluator) parseMulDiv(expr string) (*Value, error) {
 	parts := splitByOperator(expr, []string{"*", "/"})
 
 	if len(parts) == 0 {
-		return Parse(expr)
+		return e.parse(expr)
 	}
 
 	if len(parts) == 1 {
@@ -200,7 +285,7 @@ func (e *Evaluator) parseMulDiv(expr string) (*Value, error) {
 		if IsFunctionCall(valStr) {
 			return e.evalFunctionCall(valStr)
 		}
-		return Parse(valStr)
+		return e.parse(valStr)
 	}
 
 	left, err := e.parseValue(parts[0].value)
@@ -235,7 +320,11 @@ func (e *Evaluator) parseValue(expr string) (*Value, error) {
 	// Check for variable reference
 	if strings.HasPrefix(expr, "@") && !strings.ContainsAny(expr, " ()+-*/") {
 		varName := strings.TrimPrefix(expr, "@")
-		if v, ok := e.variables[varName]; ok {
+		v, ok, err := e.variable(varName)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
 			return v, nil
 		}
 	}
@@ -243,7 +332,7 @@ func (e *Evaluator) parseValue(expr string) (*Value, error) {
 	if IsFunctionCall(expr) {
 		return e.evalFunctionCall(expr)
 	}
-	return Parse(expr)
+	return e.parse(expr)
 }
 
 // opPart represents an operand with its preceding operator
@@ -268,7 +357,7 @@ func splitByOperator(expr string, ops []string) []opPart {
 		} else if ch == ')' {
 			parenDepth--
 			current.WriteByte(ch)
-		} else if parenDepth == 0 && isOperator(string(ch), ops) {
+		} else if parenDepth == 0 && isOperator(string(ch), ops) && !isUnarySign(ch, current.String()) {
 			// Found an operator at depth 0
 			parts = append(parts, opPart{
 				op:    lastOp,
@@ -291,6 +380,16 @@ func splitByOperator(expr string, ops []string) []opPart {
 	return parts
 }
 
+// isUnarySign reports whether a + or - has no left operand, making it the sign
+// of the following number, e.g. "-10px + 5px" or "10px * -1"
+func isUnarySign(ch byte, left string) bool {
+	if ch != '-' && ch != '+' {
+		return false
+	}
+	left = strings.TrimSpace(left)
+	return left == "" || strings.ContainsAny(left[len(left)-1:], "*/+-(")
+}
+
 // isOperator checks if a string is in the operator list
 func isOperator(s string, ops []string) bool {
 	for _, op := range ops {
@@ -320,12 +419,9 @@ func (e *Evaluator) evaluateEmbeddedFunctions(value string) string {
 // extractFunctions extracts all function calls from a string
 func (e *Evaluator) extractFunctions(value string) []string {
 	var functions []string
-	lessFunctions := make(map[string]bool)
-	for k := range funcMap {
-		lessFunctions[k] = true
-	}
 
-	for fnName := range lessFunctions {
+	// Iterate the sorted function names so replacements happen in a stable order
+	for _, fnName := range cachedFunctionNames {
 		searchStr := fnName + "("
 		pos := 0
 
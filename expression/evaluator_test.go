@@ -63,6 +63,23 @@ func TestEvalWithVariables(t *testing.T) {
 	}
 }
 
+func TestEvalInvalidVariable(t *testing.T) {
+	e, _ := NewEvaluator(map[string]string{"size": "1.2.3px", "base": "10px"})
+
+	for _, expr := range []string{"@size", "@size * 2", "@base + @size", "percentage(@size)"} {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := e.Eval(expr); err == nil || err.Error() != "variable @size: invalid number: 1.2.3" {
+				t.Fatalf("Eval(%s) err = %v, want invalid number error", expr, err)
+			}
+		})
+	}
+
+	v, err := e.Eval("@base * 2")
+	if err != nil || v.Number != 20 || v.Unit != "px" {
+		t.Fatalf("Eval(@base * 2) = %v, %v", v, err)
+	}
+}
+
 func TestEvalComplexExpressions(t *testing.T) {
 	e, _ := NewEvaluator(nil)
 	e.SetVariable("size", NewValue(10, "px"))
@@ -76,6 +93,8 @@ func TestEvalComplexExpressions(t *testing.T) {
 		{"@size * @multiplier", 20, "px"},
 		{"@size + @size", 20, "px"},
 		{"@size * 3 + 5px", 35, "px"},
+		{"ceil((@size / 4))", 3, "px"},
+		{"max((@size * 2), 15px)", 20, "px"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,87 @@
+package functions
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// Context holds the settings functions are evaluated with. The renderer
+// creates one for each render, so renders with different options can run
+// concurrently. The zero value, like a nil Context, uses the defaults.
+//
+// Functions reading these settings are methods of Context, and each also
+// has a package-level function of the same name using the defaults, e.g.
+// Lighten calls Context.Lighten on a nil Context.
+type Context struct {
+	// Precision is the number of decimal places numeric function and math
+	// results are rounded to before trimming zeros. Zero uses DefaultPrecision.
+	Precision int
+
+	// ModernColors emits rgb() and hsl() in the space separated syntax with
+	// a slash alpha, e.g. rgb(255 0 0 / 50%).
+	ModernColors bool
+
+	// FS holds the files read by data-uri() and the image functions. When
+	// it's nil, files are read from disk relative to BaseDir.
+	FS fs.FS
+
+	// BaseDir resolves relative image paths.
+	BaseDir string
+
+	// DataURISizeLimit is the size in bytes of the largest file data-uri()
+	// inlines, larger files are referenced with url(). Zero uses
+	// DefaultDataURISizeLimit, a negative value disables the check.
+	DataURISizeLimit int
+
+	// DefaultMatch is set while rendering a mixin variant selected through
+	// a default() guard, meaning no other variant matched.
+	DefaultMatch bool
+}
+
+func (ctx *Context) precision() int {
+	if ctx == nil || ctx.Precision <= 0 {
+		return DefaultPrecision
+	}
+	return ctx.Precision
+}
+
+func (ctx *Context) modernColors() bool {
+	return ctx != nil && ctx.ModernColors
+}
+
+func (ctx *Context) dataURISizeLimit() int {
+	if ctx == nil || ctx.DataURISizeLimit == 0 {
+		return DefaultDataURISizeLimit
+	}
+	return ctx.DataURISizeLimit
+}
+
+func (ctx *Context) defaultMatch() bool {
+	return ctx != nil && ctx.DefaultMatch
+}
+
+// resolvePath resolves a relative path against BaseDir
+func (ctx *Context) resolvePath(name string) string {
+	if ctx == nil || ctx.BaseDir == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(ctx.BaseDir, name)
+}
+
+// readFile reads a file through FS, or from disk relative to BaseDir
+func (ctx *Context) readFile(name string) ([]byte, error) {
+	if ctx != nil && ctx.FS != nil {
+		return fs.ReadFile(ctx.FS, path.Clean(name))
+	}
+	return os.ReadFile(ctx.resolvePath(name))
+}
+
+// open opens a file through FS, or from disk relative to BaseDir
+func (ctx *Context) open(name string) (fs.File, error) {
+	if ctx != nil && ctx.FS != nil {
+		return ctx.FS.Open(path.Clean(name))
+	}
+	return os.Open(ctx.resolvePath(name))
+}
@@ -0,0 +1,18 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageFunctionsUseDefaults(t *testing.T) {
+	require.Equal(t, "#4080bf", Lighten("#336699", "10%"))
+	require.Equal(t, "rgba(255, 0, 0, 0.5)", Fade("red", "50%"))
+	require.Equal(t, "33.33333333%", Percentage("0.333333333333"))
+	require.Equal(t, "false", Default())
+
+	ctx := &Context{Precision: 2, ModernColors: true}
+	require.Equal(t, "rgb(255 0 0 / 50%)", ctx.Fade("red", "50%"))
+	require.Equal(t, "33.33%", ctx.Percentage("0.333333333333"))
+}
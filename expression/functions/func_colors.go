@@ -161,6 +161,7 @@ var cssColorKeywords = map[string]string{
 	"plum":                 "#dda0dd",
 	"powderblue":           "#b0e0e6",
 	"purple":               "#800080",
+	"rebeccapurple":        "#663399",
 	"red":                  "#ff0000",
 	"rosybrown":            "#bc8f8f",
 	"royalblue":            "#4169e1",
@@ -194,6 +195,11 @@ var cssColorKeywords = map[string]string{
 // ParseHex parses a hex color string (#fff, #ffffff, #rrggbbaa)
 func ParseHex(hex string) (*Color, error) {
 	hex = strings.TrimPrefix(hex, "#")
+	for i := 0; i < len(hex); i++ {
+		if !isHexDigit(hex[i]) {
+			return nil, fmt.Errorf("invalid hex color: #%s", hex)
+		}
+	}
 
 	var r, g, b, a float64
 	a = 1.0
@@ -226,9 +232,7 @@ func ParseHex(hex string) (*Color, error) {
 
 // ParseRGB parses rgb() or rgba() format
 func ParseRGB(s string) (*Color, error) {
-	var rgba bool
 	if strings.HasPrefix(s, "rgba") {
-		rgba = true
 		s = s[5 : len(s)-1] // remove "rgba(" and ")"
 	} else if strings.HasPrefix(s, "rgb") {
 		s = s[4 : len(s)-1] // remove "rgb(" and ")"
@@ -236,16 +240,9 @@ func ParseRGB(s string) (*Color, error) {
 		return nil, fmt.Errorf("invalid rgb color: %s", s)
 	}
 
-	parts := strings.Split(s, ",")
-	if rgba && len(parts) != 4 {
-		if !rgba && len(parts) != 3 {
-			return nil, fmt.Errorf("invalid rgb color format")
-		}
-	}
-
-	// Trim spaces from parts
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
+	parts := SplitColorArgs(s)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid rgb color format")
 	}
 
 	r, err := strconv.ParseFloat(parts[0], 64)
@@ -262,8 +259,8 @@ func ParseRGB(s string) (*Color, error) {
 	}
 
 	a := 1.0
-	if rgba && len(parts) > 3 {
-		a, err = strconv.ParseFloat(parts[3], 64)
+	if len(parts) > 3 {
+		a, err = ParseAlphaArg(parts[3])
 		if err != nil {
 			return nil, err
 		}
@@ -272,12 +269,39 @@ func ParseRGB(s string) (*Color, error) {
 	return &Color{r, g, b, a}, nil
 }
 
+// SplitColorArgs splits the arguments of rgb()/hsl() in either the legacy
+// comma syntax ("255, 0, 0, 0.5") or the space syntax ("255 0 0 / 50%").
+func SplitColorArgs(s string) []string {
+	var parts []string
+	if strings.Contains(s, ",") {
+		parts = strings.Split(s, ",")
+	} else {
+		channels, alpha, hasAlpha := strings.Cut(s, "/")
+		parts = strings.Fields(channels)
+		if hasAlpha {
+			parts = append(parts, alpha)
+		}
+	}
+
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	return parts
+}
+
+// ParseAlphaArg parses an alpha channel given as a number (0.5) or a percentage (50%)
+func ParseAlphaArg(s string) (float64, error) {
+	if pct, ok := strings.CutSuffix(s, "%"); ok {
+		a, err := strconv.ParseFloat(pct, 64)
+		return a / 100, err
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
 // ParseHSL parses hsl() or hsla() format
 func ParseHSL(input string) (*Color, error) {
-	var hsla bool
 	s := input
 	if strings.HasPrefix(s, "hsla") {
-		hsla = true
 		s = s[5 : len(s)-1] // remove "hsla(" and ")"
 	} else if strings.HasPrefix(s, "hsl") {
 		s = s[4 : len(s)-1] // remove "hsl(" and ")"
@@ -285,18 +309,14 @@ func ParseHSL(input string) (*Color, error) {
 		return nil, fmt.Errorf("invalid hsl color: %s", input)
 	}
 
-	parts := strings.Split(s, ",")
-	if hsla && len(parts) != 4 {
-		if !hsla && len(parts) != 3 {
-			return nil, fmt.Errorf("invalid hsl color format")
-		}
+	parts := SplitColorArgs(s)
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("invalid hsl color format")
 	}
 
-	// Trim spaces and remove % from parts
-	for i := range parts {
-		parts[i] = strings.TrimSpace(parts[i])
-		parts[i] = strings.TrimSuffix(parts[i], "%")
-	}
+	// Remove % from saturation and lightness
+	parts[1] = strings.TrimSuffix(parts[1], "%")
+	parts[2] = strings.TrimSuffix(parts[2], "%")
 
 	h, err := strconv.ParseFloat(parts[0], 64)
 	if err != nil {
@@ -312,8 +332,8 @@ func ParseHSL(input string) (*Color, error) {
 	}
 
 	a := 1.0
-	if hsla && len(parts) > 3 {
-		a, err = strconv.ParseFloat(parts[3], 64)
+	if len(parts) > 3 {
+		a, err = ParseAlphaArg(parts[3])
 		if err != nil {
 			return nil, err
 		}
@@ -336,11 +356,24 @@ func parseHexByte(h string) float64 {
 	return float64(n)
 }
 
+// roundChannel rounds an RGB channel to an integer, clamped to 0-255.
+// Values are snapped to 1e-7 first so float drift (127.49999999) doesn't
+// flip the rounding direction of exact half steps.
+func roundChannel(v float64) uint8 {
+	v = math.Round(math.Round(v*1e7) / 1e7)
+	return uint8(math.Max(0, math.Min(255, v)))
+}
+
+// Channels returns the red, green and blue channels rounded to 0-255
+func (c *Color) Channels() (r, g, b uint8) {
+	return roundChannel(c.R), roundChannel(c.G), roundChannel(c.B)
+}
+
 // ToHex returns the color as a hex string
 func (c *Color) ToHex() string {
-	r := uint8(math.Round(c.R))
-	g := uint8(math.Round(c.G))
-	b := uint8(math.Round(c.B))
+	r := roundChannel(c.R)
+	g := roundChannel(c.G)
+	b := roundChannel(c.B)
 
 	if c.A < 1.0 {
 		a := uint8(math.Round(c.A * 255))
@@ -350,49 +383,89 @@ func (c *Color) ToHex() string {
 	return fmt.Sprintf("#%02x%02x%02x", r, g, b)
 }
 
-// ToRGB returns the color as rgb() or rgba() format
-func (c *Color) ToRGB() string {
-	r := uint8(math.Round(c.R))
-	g := uint8(math.Round(c.G))
-	b := uint8(math.Round(c.B))
+// ToRGB returns the color as rgb() or rgba() format, in the syntax of ctx
+func (c *Color) ToRGB(ctx *Context) string {
+	r := roundChannel(c.R)
+	g := roundChannel(c.G)
+	b := roundChannel(c.B)
 
-	if c.A < 1.0 {
-		return fmt.Sprintf("rgba(%d, %d, %d, %g)", r, g, b, c.A)
-	}
-
-	return fmt.Sprintf("rgb(%d, %d, %d)", r, g, b)
+	return ctx.FormatRGB(r, g, b, c.A, c.A < 1.0)
 }
 
 // Lighten lightens a color by a percentage
 func (c *Color) Lighten(amount float64) *Color {
+	// A no-op keeps the exact input channels
+	if amount == 0 {
+		return c.copy()
+	}
 	h, s, l := c.ToHSL()
 	l = math.Min(1.0, l+amount)
 	return HSLToColor(h, s, l, c.A)
 }
 
+// LightenRelative lightens a color by a proportion of its lightness
+func (c *Color) LightenRelative(amount float64) *Color {
+	_, _, l := c.ToHSL()
+	return c.Lighten(l * amount)
+}
+
 // Darken darkens a color by a percentage
 func (c *Color) Darken(amount float64) *Color {
+	// A no-op keeps the exact input channels
+	if amount == 0 {
+		return c.copy()
+	}
 	h, s, l := c.ToHSL()
 	l = math.Max(0.0, l-amount)
 	return HSLToColor(h, s, l, c.A)
 }
 
+// DarkenRelative darkens a color by a proportion of its lightness
+func (c *Color) DarkenRelative(amount float64) *Color {
+	_, _, l := c.ToHSL()
+	return c.Darken(l * amount)
+}
+
 // Saturate increases saturation
 func (c *Color) Saturate(amount float64) *Color {
+	// A no-op keeps the exact input channels
+	if amount == 0 {
+		return c.copy()
+	}
 	h, s, l := c.ToHSL()
 	s = math.Min(1.0, s+amount)
 	return HSLToColor(h, s, l, c.A)
 }
 
+// SaturateRelative increases saturation by a proportion of the current value
+func (c *Color) SaturateRelative(amount float64) *Color {
+	_, s, _ := c.ToHSL()
+	return c.Saturate(s * amount)
+}
+
 // Desaturate decreases saturation
 func (c *Color) Desaturate(amount float64) *Color {
+	// A no-op keeps the exact input channels
+	if amount == 0 {
+		return c.copy()
+	}
 	h, s, l := c.ToHSL()
 	s = math.Max(0.0, s-amount)
 	return HSLToColor(h, s, l, c.A)
 }
 
+// DesaturateRelative decreases saturation by a proportion of the current value
+func (c *Color) DesaturateRelative(amount float64) *Color {
+	_, s, _ := c.ToHSL()
+	return c.Desaturate(s * amount)
+}
+
 // Spin rotates the hue
 func (c *Color) Spin(degrees float64) *Color {
+	// A no-op keeps the exact input channels
+	if degrees == 0 {
+		return c.copy()
+	}
 	h, s, l := c.ToHSL()
 	h = math.Mod(h+degrees, 360)
 	if h < 0 {
@@ -401,7 +474,18 @@ func (c *Color) Spin(degrees float64) *Color {
 	return HSLToColor(h, s, l, c.A)
 }
 
-// Mix mixes two colors
+// isRelative checks if the optional method argument is "relative"
+func isRelative(method []string) bool {
+	return len(method) > 0 && strings.TrimSpace(method[0]) == "relative"
+}
+
+// copy returns a copy of the color
+func (c *Color) copy() *Color {
+	result := *c
+	return &result
+}
+
+// Mix mixes two colors, weight is the proportion of other
 func (c *Color) Mix(other *Color, weight float64) *Color {
 	weight = math.Max(0, math.Min(1, weight))
 	return &Color{
@@ -615,9 +699,29 @@ func RGB(r, g, b string) string {
 	return color.ToHex()
 }
 
+// RGBA sets the alpha of a color given as a keyword, hex or function, e.g.
+// rgba(red, 0.5). The four channel form isn't a function call and is kept as is.
+func (ctx *Context) RGBA(colorStr, alpha string) string {
+	color, err := ParseColor(colorStr)
+	if err != nil {
+		return "rgba(" + colorStr + ", " + alpha + ")"
+	}
+	a, err := ParseAlphaArg(alpha)
+	if err != nil {
+		return "rgba(" + colorStr + ", " + alpha + ")"
+	}
+	a = math.Max(0, math.Min(1, a))
+	return ctx.FormatRGB(uint8(math.Round(color.R)), uint8(math.Round(color.G)), uint8(math.Round(color.B)), a, true)
+}
+
+// RGBA is Context.RGBA with the default settings
+func RGBA(colorStr, alpha string) string {
+	return (*Context)(nil).RGBA(colorStr, alpha)
+}
+
 // HSL creates a color from HSL components (hue 0-360, saturation 0-100, lightness 0-100)
 // Returns in hsl() format, not hex
-func HSL(h, s, l string) string {
+func (ctx *Context) HSL(h, s, l string) string {
 	hNum := parseNumber(h)
 	sNum := parseNumber(s) / 100.0 // Convert from percentage to 0-1
 	lNum := parseNumber(l) / 100.0 // Convert from percentage to 0-1
@@ -631,12 +735,17 @@ func HSL(h, s, l string) string {
 	lNum = math.Max(0, math.Min(1, lNum))
 
 	// Return in hsl() format
-	return fmt.Sprintf("hsl(%g, %g%%, %g%%)", hNum, sNum*100, lNum*100)
+	return ctx.FormatHSL(hNum, sNum*100, lNum*100, 1, false)
+}
+
+// HSL is Context.HSL with the default settings
+func HSL(h, s, l string) string {
+	return (*Context)(nil).HSL(h, s, l)
 }
 
 // HSLA creates a color from HSLA components (hue 0-360, saturation 0-100, lightness 0-100, alpha 0-1)
 // Returns in hsla() format
-func HSLA(h, s, l, a string) string {
+func (ctx *Context) HSLA(h, s, l, a string) string {
 	hNum := parseNumber(h)
 	sNum := parseNumber(s) / 100.0 // Convert from percentage to 0-1
 	lNum := parseNumber(l) / 100.0 // Convert from percentage to 0-1
@@ -652,7 +761,12 @@ func HSLA(h, s, l, a string) string {
 	aNum = math.Max(0, math.Min(1, aNum))
 
 	// Return in hsla() format
-	return fmt.Sprintf("hsla(%g, %g%%, %g%%, %g)", hNum, sNum*100, lNum*100, aNum)
+	return ctx.FormatHSL(hNum, sNum*100, lNum*100, aNum, true)
+}
+
+// HSLA is Context.HSLA with the default settings
+func HSLA(h, s, l, a string) string {
+	return (*Context)(nil).HSLA(h, s, l, a)
 }
 
 // Hue extracts the hue component (0-360) from a color
@@ -685,6 +799,72 @@ func Lightness(colorStr string) string {
 	return strconv.FormatFloat(l*100, 'f', -1, 64) + "%"
 }
 
+// SetHue returns the color with its hue set to degrees (0-360)
+func (ctx *Context) SetHue(colorStr, degrees string) string {
+	color, err := ParseColor(colorStr)
+	if err != nil {
+		return colorStr
+	}
+	_, s, l := color.ToHSL()
+	return ctx.formatColor(colorStr, HSLToColor(parseNumber(degrees), s, l, color.A))
+}
+
+// SetHue is Context.SetHue with the default settings
+func SetHue(colorStr, degrees string) string {
+	return (*Context)(nil).SetHue(colorStr, degrees)
+}
+
+// SetSaturation returns the color with its saturation set to amount (0-100%)
+func (ctx *Context) SetSaturation(colorStr, amount string) string {
+	color, err := ParseColor(colorStr)
+	if err != nil {
+		return colorStr
+	}
+	h, _, l := color.ToHSL()
+	s := math.Max(0, math.Min(1, parseNumber(amount)/100))
+	return ctx.formatColor(colorStr, HSLToColor(h, s, l, color.A))
+}
+
+// SetSaturation is Context.SetSaturation with the default settings
+func SetSaturation(colorStr, amount string) string {
+	return (*Context)(nil).SetSaturation(colorStr, amount)
+}
+
+// SetLightness returns the color with its lightness set to amount (0-100%)
+func (ctx *Context) SetLightness(colorStr, amount string) string {
+	color, err := ParseColor(colorStr)
+	if err != nil {
+		return colorStr
+	}
+	h, s, _ := color.ToHSL()
+	l := math.Max(0, math.Min(1, parseNumber(amount)/100))
+	return ctx.formatColor(colorStr, HSLToColor(h, s, l, color.A))
+}
+
+// SetLightness is Context.SetLightness with the default settings
+func SetLightness(colorStr, amount string) string {
+	return (*Context)(nil).SetLightness(colorStr, amount)
+}
+
+// SetAlpha returns the color with its alpha set to amount, given as 0-1 or 0-100%
+func (ctx *Context) SetAlpha(colorStr, amount string) string {
+	color, err := ParseColor(colorStr)
+	if err != nil {
+		return colorStr
+	}
+	alpha := parseNumber(amount)
+	if strings.HasSuffix(strings.TrimSpace(amount), "%") {
+		alpha /= 100
+	}
+	color.A = math.Max(0, math.Min(1, alpha))
+	return ctx.formatColor(colorStr, color)
+}
+
+// SetAlpha is Context.SetAlpha with the default settings
+func SetAlpha(colorStr, amount string) string {
+	return (*Context)(nil).SetAlpha(colorStr, amount)
+}
+
 // Red extracts the red channel (0-255) from a color
 func Red(colorStr string) string {
 	color, err := ParseColor(colorStr)
@@ -722,19 +902,24 @@ func Alpha(colorStr string) string {
 }
 
 // LumaFunction returns the perceived brightness (luminance) of a color as a string
-func LumaFunction(colorStr string) string {
+func (ctx *Context) LumaFunction(colorStr string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return "0"
 	}
 
 	lum := color.Luma()
-	// Round to 8 decimal places to match LESS output
-	return strconv.FormatFloat(math.Round(lum*100000000)/100000000, 'f', -1, 64) + "%"
+	// Round to Precision decimal places to match LESS output
+	return strconv.FormatFloat(ctx.RoundPrecision(lum), 'f', -1, 64) + "%"
+}
+
+// LumaFunction is Context.LumaFunction with the default settings
+func LumaFunction(colorStr string) string {
+	return (*Context)(nil).LumaFunction(colorStr)
 }
 
 // Luminance calculates the luminance of a color (without gamma correction)
-func Luminance(colorStr string) string {
+func (ctx *Context) Luminance(colorStr string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return "0"
@@ -748,12 +933,17 @@ func Luminance(colorStr string) string {
 	// ITU-R BT.709 luminance without gamma correction
 	lum := 0.2126*r + 0.7152*g + 0.0722*b
 	lumPercent := lum * 100
-	// Round to 8 decimal places to match LESS output
-	return strconv.FormatFloat(math.Round(lumPercent*100000000)/100000000, 'f', -1, 64) + "%"
+	// Round to Precision decimal places to match LESS output
+	return strconv.FormatFloat(ctx.RoundPrecision(lumPercent), 'f', -1, 64) + "%"
+}
+
+// Luminance is Context.Luminance with the default settings
+func Luminance(colorStr string) string {
+	return (*Context)(nil).Luminance(colorStr)
 }
 
 // Fade sets the opacity of a color (0-100%)
-func Fade(colorStr, amount string) string {
+func (ctx *Context) Fade(colorStr, amount string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
@@ -763,57 +953,86 @@ func Fade(colorStr, amount string) string {
 	amountNum = math.Max(0, math.Min(1, amountNum))
 
 	color.A = amountNum
-	return formatColor(colorStr, color)
+	return ctx.formatColor(colorStr, color)
+}
+
+// Fade is Context.Fade with the default settings
+func Fade(colorStr, amount string) string {
+	return (*Context)(nil).Fade(colorStr, amount)
 }
 
 // Fadein increases opacity
-func Fadein(colorStr, amount string) string {
+func (ctx *Context) Fadein(colorStr, amount string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 
 	amountNum := parseNumber(amount) / 100.0 // Convert percentage
-	color.A = math.Min(1, color.A+amountNum)
+	color.A = math.Max(0, math.Min(1, color.A+amountNum))
 
-	return formatColor(colorStr, color)
+	return ctx.formatColor(colorStr, color)
+}
+
+// Fadein is Context.Fadein with the default settings
+func Fadein(colorStr, amount string) string {
+	return (*Context)(nil).Fadein(colorStr, amount)
 }
 
 // Fadeout decreases opacity
-func Fadeout(colorStr, amount string) string {
+func (ctx *Context) Fadeout(colorStr, amount string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 
 	amountNum := parseNumber(amount) / 100.0 // Convert percentage
-	color.A = math.Max(0, color.A-amountNum)
+	color.A = math.Max(0, math.Min(1, color.A-amountNum))
+
+	return ctx.formatColor(colorStr, color)
+}
+
+// Fadeout is Context.Fadeout with the default settings
+func Fadeout(colorStr, amount string) string {
+	return (*Context)(nil).Fadeout(colorStr, amount)
+}
 
-	return formatColor(colorStr, color)
+// parseWeight parses an optional mix weight, defaulting to 50%.
+// Percentages ("20%") and numbers above 1 are divided by 100, fractions ("0.2") are used as is.
+func parseWeight(weight []string) float64 {
+	if len(weight) == 0 || strings.TrimSpace(weight[0]) == "" {
+		return 0.5
+	}
+	value := strings.TrimSpace(weight[0])
+	num := parseNumber(value)
+	if strings.HasSuffix(value, "%") || num > 1 {
+		num /= 100.0
+	}
+	return math.Max(0, math.Min(1, num))
 }
 
-// Tint mixes a color with white
-func Tint(colorStr, weight string) string {
+// Tint mixes a color with white, weight defaults to 50%
+func Tint(colorStr string, weight ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 
-	weightNum := parseNumber(weight) / 100.0 // Convert percentage
+	weightNum := parseWeight(weight)
 	white := &Color{255, 255, 255, 1}
 	mixed := color.Mix(white, weightNum)
 
 	return mixed.ToHex()
 }
 
-// Shade mixes a color with black
-func Shade(colorStr, weight string) string {
+// Shade mixes a color with black, weight defaults to 50%
+func Shade(colorStr string, weight ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 
-	weightNum := parseNumber(weight) / 100.0 // Convert percentage
+	weightNum := parseWeight(weight)
 	black := &Color{0, 0, 0, 1}
 	mixed := color.Mix(black, weightNum)
 
@@ -1126,8 +1345,11 @@ func Unit(args ...string) string {
 // GetUnit returns the unit of a dimension as a string (without quotes)
 func GetUnit(value string) string {
 	value = strings.TrimSpace(value)
-	unit := extractUnit(value)
-	return unit
+	// Keywords and pure numbers have no unit
+	if !IsNumber(value) {
+		return ""
+	}
+	return extractUnit(value)
 }
 
 // Convert converts a number to a different unit
@@ -1198,90 +1420,169 @@ func parseAlpha(s string) float64 {
 
 // Public wrapper functions for use by renderer
 
-// roundHSLValue rounds HSL component values to 8 decimal places to avoid floating point artifacts
+// roundHSLValue rounds HSL component values to Precision decimal places to avoid floating point artifacts
 // This prevents output like "89.99999999999999%" instead of "90%"
-func roundHSLValue(val float64) float64 {
-	return math.Round(val*100000000) / 100000000
+func (ctx *Context) roundHSLValue(val float64) float64 {
+	return ctx.RoundPrecision(val)
+}
+
+// FormatRGB formats an rgb() color, or rgba() in the legacy syntax when alpha is set
+func (ctx *Context) FormatRGB(r, g, b uint8, a float64, alpha bool) string {
+	if ctx.modernColors() {
+		if a < 1.0 {
+			return fmt.Sprintf("rgb(%d %d %d / %g%%)", r, g, b, ctx.roundHSLValue(a*100))
+		}
+		return fmt.Sprintf("rgb(%d %d %d)", r, g, b)
+	}
+	if alpha {
+		return fmt.Sprintf("rgba(%d, %d, %d, %g)", r, g, b, ctx.RoundPrecision(a))
+	}
+	return fmt.Sprintf("rgb(%d, %d, %d)", r, g, b)
+}
+
+// FormatRGB is Context.FormatRGB with the default settings
+func FormatRGB(r, g, b uint8, a float64, alpha bool) string {
+	return (*Context)(nil).FormatRGB(r, g, b, a, alpha)
+}
+
+// FormatHSL formats an hsl() color with saturation and lightness in percent,
+// or hsla() in the legacy syntax when alpha is set
+func (ctx *Context) FormatHSL(h, s, l, a float64, alpha bool) string {
+	if ctx.modernColors() {
+		if a < 1.0 {
+			return fmt.Sprintf("hsl(%g %g%% %g%% / %g%%)", h, s, l, ctx.roundHSLValue(a*100))
+		}
+		return fmt.Sprintf("hsl(%g %g%% %g%%)", h, s, l)
+	}
+	if alpha {
+		return fmt.Sprintf("hsla(%g, %g%%, %g%%, %g)", h, s, l, ctx.RoundPrecision(a))
+	}
+	return fmt.Sprintf("hsl(%g, %g%%, %g%%)", h, s, l)
+}
+
+// FormatHSL is Context.FormatHSL with the default settings
+func FormatHSL(h, s, l, a float64, alpha bool) string {
+	return (*Context)(nil).FormatHSL(h, s, l, a, alpha)
 }
 
 // formatColor returns the color in the same format as the input string
-func formatColor(colorStr string, result *Color) string {
+func (ctx *Context) formatColor(colorStr string, result *Color) string {
 	switch {
 	case strings.HasPrefix(colorStr, "hsla"):
 		h, s, l := result.ToHSL()
-		s = roundHSLValue(s * 100)
-		l = roundHSLValue(l * 100)
-		return fmt.Sprintf("hsla(%g, %g%%, %g%%, %g)", h, s, l, result.A)
+		s = ctx.roundHSLValue(s * 100)
+		l = ctx.roundHSLValue(l * 100)
+		return ctx.FormatHSL(h, s, l, result.A, true)
 	case strings.HasPrefix(colorStr, "hsl"):
 		h, s, l := result.ToHSL()
-		s = roundHSLValue(s * 100)
-		l = roundHSLValue(l * 100)
-		return fmt.Sprintf("hsl(%g, %g%%, %g%%)", h, s, l)
+		s = ctx.roundHSLValue(s * 100)
+		l = ctx.roundHSLValue(l * 100)
+		return ctx.FormatHSL(h, s, l, result.A, false)
 	case strings.HasPrefix(colorStr, "rgba"):
-		return fmt.Sprintf("rgba(%d, %d, %d, %g)", uint8(result.R), uint8(result.G), uint8(result.B), result.A)
+		return ctx.FormatRGB(roundChannel(result.R), roundChannel(result.G), roundChannel(result.B), result.A, true)
 	case strings.HasPrefix(colorStr, "rgb"):
-		return fmt.Sprintf("rgb(%d, %d, %d)", uint8(result.R), uint8(result.G), uint8(result.B))
+		return ctx.FormatRGB(roundChannel(result.R), roundChannel(result.G), roundChannel(result.B), result.A, false)
+	case result.A < 1:
+		// Hex and keyword colors which became transparent, e.g. fadeout(#f00, 50%)
+		return ctx.FormatRGB(roundChannel(result.R), roundChannel(result.G), roundChannel(result.B), result.A, true)
 	default:
 		return result.ToHex()
 	}
 }
 
 // Lighten lightens a color by a percentage
-func Lighten(colorStr, amount string) string {
+func (ctx *Context) Lighten(colorStr, amount string, method ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	amountVal := parseNumber(amount) / 100.0
 	result := color.Lighten(amountVal)
-	return formatColor(colorStr, result)
+	if isRelative(method) {
+		result = color.LightenRelative(amountVal)
+	}
+	return ctx.formatColor(colorStr, result)
+}
+
+// Lighten is Context.Lighten with the default settings
+func Lighten(colorStr, amount string, method ...string) string {
+	return (*Context)(nil).Lighten(colorStr, amount, method...)
 }
 
 // Darken darkens a color by a percentage
-func Darken(colorStr, amount string) string {
+func (ctx *Context) Darken(colorStr, amount string, method ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	amountVal := parseNumber(amount) / 100.0
 	result := color.Darken(amountVal)
-	return formatColor(colorStr, result)
+	if isRelative(method) {
+		result = color.DarkenRelative(amountVal)
+	}
+	return ctx.formatColor(colorStr, result)
+}
+
+// Darken is Context.Darken with the default settings
+func Darken(colorStr, amount string, method ...string) string {
+	return (*Context)(nil).Darken(colorStr, amount, method...)
 }
 
 // Saturate increases saturation
-func Saturate(colorStr, amount string) string {
+func (ctx *Context) Saturate(colorStr, amount string, method ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	amountVal := parseNumber(amount) / 100.0
 	result := color.Saturate(amountVal)
-	return formatColor(colorStr, result)
+	if isRelative(method) {
+		result = color.SaturateRelative(amountVal)
+	}
+	return ctx.formatColor(colorStr, result)
+}
+
+// Saturate is Context.Saturate with the default settings
+func Saturate(colorStr, amount string, method ...string) string {
+	return (*Context)(nil).Saturate(colorStr, amount, method...)
 }
 
 // Desaturate decreases saturation
-func Desaturate(colorStr, amount string) string {
+func (ctx *Context) Desaturate(colorStr, amount string, method ...string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	amountVal := parseNumber(amount) / 100.0
 	result := color.Desaturate(amountVal)
-	return formatColor(colorStr, result)
+	if isRelative(method) {
+		result = color.DesaturateRelative(amountVal)
+	}
+	return ctx.formatColor(colorStr, result)
+}
+
+// Desaturate is Context.Desaturate with the default settings
+func Desaturate(colorStr, amount string, method ...string) string {
+	return (*Context)(nil).Desaturate(colorStr, amount, method...)
 }
 
 // Spin rotates the hue
-func Spin(colorStr, degrees string) string {
+func (ctx *Context) Spin(colorStr, degrees string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	angleVal := parseNumber(degrees)
 	result := color.Spin(angleVal)
-	return formatColor(colorStr, result)
+	return ctx.formatColor(colorStr, result)
+}
+
+// Spin is Context.Spin with the default settings
+func Spin(colorStr, degrees string) string {
+	return (*Context)(nil).Spin(colorStr, degrees)
 }
 
-// Mix mixes two colors
+// Mix mixes two colors, the weight is the proportion of the second color
 func Mix(color1Str, color2Str string, args ...string) string {
 	c1, err1 := ParseColor(color1Str)
 	c2, err2 := ParseColor(color2Str)
@@ -1305,13 +1606,18 @@ func Mix(color1Str, color2Str string, args ...string) string {
 }
 
 // Greyscale returns the greyscale version of the color
-func Greyscale(colorStr string) string {
+func (ctx *Context) Greyscale(colorStr string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	result := color.Greyscale()
-	return formatColor(colorStr, result)
+	return ctx.formatColor(colorStr, result)
+}
+
+// Greyscale is Context.Greyscale with the default settings
+func Greyscale(colorStr string) string {
+	return (*Context)(nil).Greyscale(colorStr)
 }
 
 // HSV creates a color from HSV values, returning it as hex
@@ -1324,13 +1630,18 @@ func HSV(h, s, v string) string {
 }
 
 // HSVA creates a color from HSVA values, returning it as rgba
-func HSVA(h, s, v, a string) string {
+func (ctx *Context) HSVA(h, s, v, a string) string {
 	hVal := parseNumber(h)
 	sVal := parseNumber(s) / 100.0
 	vVal := parseNumber(v) / 100.0
 	aVal := parseNumber(a)
 	result := HSVToColor(hVal, sVal, vVal, aVal)
-	return fmt.Sprintf("rgba(%d, %d, %d, %g)", uint8(math.Round(result.R)), uint8(math.Round(result.G)), uint8(math.Round(result.B)), result.A)
+	return ctx.FormatRGB(uint8(math.Round(result.R)), uint8(math.Round(result.G)), uint8(math.Round(result.B)), result.A, true)
+}
+
+// HSVA is Context.HSVA with the default settings
+func HSVA(h, s, v, a string) string {
+	return (*Context)(nil).HSVA(h, s, v, a)
 }
 
 // ARGB returns a color in #ARGB format (alpha in first position)
@@ -1359,21 +1670,31 @@ func HSVHue(colorStr string) string {
 }
 
 // HSVSaturation extracts the saturation from a color (HSV saturation)
-func HSVSaturation(colorStr string) string {
+func (ctx *Context) HSVSaturation(colorStr string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	_, s, _ := color.ToHSV()
-	return fmt.Sprintf("%g%%", roundHSLValue(s*100))
+	return fmt.Sprintf("%g%%", ctx.roundHSLValue(s*100))
+}
+
+// HSVSaturation is Context.HSVSaturation with the default settings
+func HSVSaturation(colorStr string) string {
+	return (*Context)(nil).HSVSaturation(colorStr)
 }
 
 // HSVValue extracts the value (brightness) from a color (HSV value)
-func HSVValue(colorStr string) string {
+func (ctx *Context) HSVValue(colorStr string) string {
 	color, err := ParseColor(colorStr)
 	if err != nil {
 		return colorStr
 	}
 	_, _, v := color.ToHSV()
-	return fmt.Sprintf("%g%%", roundHSLValue(v*100))
+	return fmt.Sprintf("%g%%", ctx.roundHSLValue(v*100))
+}
+
+// HSVValue is Context.HSVValue with the default settings
+func HSVValue(colorStr string) string {
+	return (*Context)(nil).HSVValue(colorStr)
 }
@@ -0,0 +1,124 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Expected values are taken from less.js output
+func TestColorOperationsGolden(t *testing.T) {
+	ctx := &Context{}
+	// Wrap the variadic signatures for the table
+	Lighten := func(c, a string) string { return ctx.Lighten(c, a) }
+	Darken := func(c, a string) string { return ctx.Darken(c, a) }
+	Saturate := func(c, a string) string { return ctx.Saturate(c, a) }
+	Desaturate := func(c, a string) string { return ctx.Desaturate(c, a) }
+
+	tests := []struct {
+		name     string
+		fn       func(string, string) string
+		color    string
+		amount   string
+		expected string
+	}{
+		{"lighten black 0%", Lighten, "#000000", "0%", "#000000"},
+		{"lighten 0%", Lighten, "#336699", "0%", "#336699"},
+		{"lighten near white 0%", Lighten, "#fefefe", "0%", "#fefefe"},
+		{"lighten odd channels 0%", Lighten, "#123457", "0%", "#123457"},
+		{"lighten 10%", Lighten, "#336699", "10%", "#4080bf"},
+		{"lighten 100%", Lighten, "#336699", "100%", "#ffffff"},
+		{"darken 0%", Darken, "#336699", "0%", "#336699"},
+		{"darken 10%", Darken, "#336699", "10%", "#264d73"},
+		{"darken 100%", Darken, "#336699", "100%", "#000000"},
+		{"saturate 0%", Saturate, "#336699", "0%", "#336699"},
+		{"saturate 20%", Saturate, "#336699", "20%", "#1f66ad"},
+		{"desaturate 0%", Desaturate, "#fefffe", "0%", "#fefffe"},
+		{"desaturate 100%", Desaturate, "#336699", "100%", "#666666"},
+		{"spin 0", ctx.Spin, "#336699", "0", "#336699"},
+		{"spin 360", ctx.Spin, "#336699", "360", "#336699"},
+		{"rgb output rounds", Lighten, "rgb(51, 102, 153)", "10%", "rgb(64, 128, 191)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.fn(tt.color, tt.amount))
+		})
+	}
+}
+
+func TestMixGolden(t *testing.T) {
+	require.Equal(t, "#800080", Mix("#ff0000", "#0000ff"))
+	require.Equal(t, "#4000bf", Mix("#ff0000", "#0000ff", "75%"))
+	require.Equal(t, "#0000ff", Mix("#ff0000", "#0000ff", "100%"))
+	require.Equal(t, "#fefefe", Mix("#fefefe", "#fefefe", "33%"))
+}
+
+func TestColorOperationsRelative(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "#3870a8", ctx.Lighten("#336699", "10%", "relative"))
+	require.Equal(t, "#2e5c8a", ctx.Darken("#336699", "10%", "relative"))
+	require.Equal(t, "#2966a3", ctx.Saturate("#336699", "20%", "relative"))
+	require.Equal(t, "#3d668f", ctx.Desaturate("#336699", "20%", "relative"))
+	require.Equal(t, "#4080bf", ctx.Lighten("#336699", "10%", "absolute"))
+}
+
+func TestTintShadeWeight(t *testing.T) {
+	require.Equal(t, "#808080", Tint("#000"))
+	require.Equal(t, "#808080", Tint("#000", "50%"))
+	require.Equal(t, "#333333", Tint("#000", "20%"))
+	require.Equal(t, "#333333", Tint("#000", "0.2"))
+	require.Equal(t, "#333333", Tint("#000", "20"))
+	require.Equal(t, "#808080", Shade("#fff"))
+	require.Equal(t, "#cccccc", Shade("#fff", "0.2"))
+	require.Equal(t, "#cccccc", Shade("#fff", "20%"))
+}
+
+func TestModernColorSyntax(t *testing.T) {
+	ctx := &Context{ModernColors: true}
+
+	require.Equal(t, "rgb(255 0 0 / 50%)", ctx.Fadeout("rgb(255, 0, 0)", "50%"))
+	require.Equal(t, "hsl(120 50% 50% / 50%)", ctx.HSLA("120", "50%", "50%", "0.5"))
+	require.Equal(t, "hsl(120 50% 50%)", ctx.HSL("120", "50%", "50%"))
+	require.Equal(t, "rgb(54 108 160)", ctx.Lighten("rgb(41 82 122)", "10%"))
+
+	color, err := ParseColor("rgb(255 0 0 / 25%)")
+	require.NoError(t, err)
+	require.Equal(t, &Color{255, 0, 0, 0.25}, color)
+}
+
+func TestFadeHexInputs(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "rgba(255, 0, 0, 0.5)", ctx.Fadeout("#ff0000", "50%"))
+	require.Equal(t, "rgba(255, 0, 0, 0.3)", ctx.Fade("#f00", "30%"))
+	require.Equal(t, "rgba(255, 0, 0, 0.8)", ctx.Fadeout("red", "20%"))
+	require.Equal(t, "rgba(255, 0, 0, 0)", ctx.Fadeout("#ff0000", "150%"))
+	require.Equal(t, "rgba(255, 0, 0, 0.6)", ctx.Fadein("#ff000033", "40%"))
+	require.Equal(t, "#ff0000", ctx.Fadein("#ff000080", "80%"))
+	require.Equal(t, "#ff0000", ctx.Fade("#ff0000", "100%"))
+	require.Equal(t, "#ff0000", ctx.SetAlpha("#ff0000", "1"))
+	require.Equal(t, "rgba(255, 0, 0, 0.5)", ctx.SetAlpha("#ff0000", "0.5"))
+}
+
+func TestColorKeywords(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "#ff3333", ctx.Lighten("red", "10%"))
+	require.Equal(t, "#0000cc", ctx.Darken("Blue", "10%"))
+	require.Equal(t, "#800080", Mix("red", "blue"))
+	require.Equal(t, "rgba(255, 0, 0, 0.5)", ctx.RGBA("red", "0.5"))
+	require.Equal(t, "rgba(0, 0, 255, 0.25)", ctx.RGBA("blue", "25%"))
+	require.Equal(t, "rgba(var(--c), 0.5)", ctx.RGBA("var(--c)", "0.5"))
+	require.True(t, IsColor("rebeccapurple"))
+	require.True(t, IsColor("Blue"))
+	require.False(t, IsColor("bold"))
+}
+
+func TestColorSetters(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "#00ff00", ctx.SetHue("red", "120"))
+	require.Equal(t, "#808080", ctx.SetSaturation("#ff0000", "0%"))
+	require.Equal(t, "#ffffff", ctx.SetLightness("#336699", "100%"))
+	require.Equal(t, "rgba(255, 0, 0, 0.5)", ctx.SetAlpha("rgba(255, 0, 0, 1)", "0.5"))
+	require.Equal(t, "rgba(255, 0, 0, 0.25)", ctx.SetAlpha("rgba(255, 0, 0, 1)", "25%"))
+	require.Equal(t, "bold", ctx.SetHue("bold", "120"))
+}
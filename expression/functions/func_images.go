@@ -1,28 +1,76 @@
 package functions
 
 import (
+	"encoding/base64"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
-	"os"
+	"mime"
 	"path/filepath"
 	"strings"
 	"sync"
 )
 
+// DefaultDataURISizeLimit is the largest file data-uri() inlines by default,
+// 32KB like the IE8 limit less.js checks for with ieCompat
+const DefaultDataURISizeLimit = 32 * 1024
+
 var (
 	// Image dimension cache to avoid re-reading files
 	imageDimCache = make(map[string][2]int)
 	imageDimMutex = sync.RWMutex{}
-
-	// BaseDir is set by the renderer to enable resolving relative image paths
-	BaseDir string
 )
 
+// DataURI inlines a file as a data URI: data-uri("image.png") or
+// data-uri("image/svg+xml", "icon.svg"). Without a mimetype it's guessed
+// from the extension, and SVG and text files are URL encoded instead of
+// base64 encoded. Files over the DataURISizeLimit of ctx fall back to
+// url("file").
+func (ctx *Context) DataURI(args ...string) (string, error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", fmt.Errorf("data-uri: expected 1 or 2 arguments, got %d", len(args))
+	}
+	filePath := strings.Trim(args[len(args)-1], "'\"")
+
+	data, err := ctx.readFile(filePath)
+	if err != nil {
+		return "", fmt.Errorf("data-uri: cannot read file %s: %w", filePath, err)
+	}
+	if limit := ctx.dataURISizeLimit(); limit > 0 && len(data) > limit {
+		return fmt.Sprintf("url(\"%s\")", filePath), nil
+	}
+
+	var mimetype string
+	var useBase64 bool
+	if len(args) == 2 {
+		mimetype = strings.Trim(args[0], "'\"")
+		useBase64 = strings.HasSuffix(mimetype, ";base64")
+	} else {
+		mimetype, _, _ = strings.Cut(mime.TypeByExtension(filepath.Ext(filePath)), ";")
+		if mimetype == "" {
+			mimetype = "application/octet-stream"
+		}
+		useBase64 = mimetype != "image/svg+xml" && !strings.HasPrefix(mimetype, "text/")
+		if useBase64 {
+			mimetype += ";base64"
+		}
+	}
+
+	if useBase64 {
+		return fmt.Sprintf("url(\"data:%s,%s\")", mimetype, base64.StdEncoding.EncodeToString(data)), nil
+	}
+	return fmt.Sprintf("url(\"data:%s,%s\")", mimetype, encodeURIComponent(string(data))), nil
+}
+
+// DataURI is Context.DataURI with the default settings
+func DataURI(args ...string) (string, error) {
+	return (*Context)(nil).DataURI(args...)
+}
+
 // ImageWidth returns the width of an image file in pixels
-func ImageWidth(filePath string) (string, error) {
+func (ctx *Context) ImageWidth(filePath string) (string, error) {
 	filePath = strings.Trim(filePath, "'\"")
 
 	// Check for external URLs
@@ -30,7 +78,7 @@ func ImageWidth(filePath string) (string, error) {
 		return "", fmt.Errorf("image-width: external URLs not yet supported (tried %s)", filePath)
 	}
 
-	width, _, err := getImageDimensions(filePath)
+	width, _, err := ctx.getImageDimensions(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -38,8 +86,13 @@ func ImageWidth(filePath string) (string, error) {
 	return fmt.Sprintf("%dpx", width), nil
 }
 
+// ImageWidth is Context.ImageWidth with the default settings
+func ImageWidth(filePath string) (string, error) {
+	return (*Context)(nil).ImageWidth(filePath)
+}
+
 // ImageHeight returns the height of an image file in pixels
-func ImageHeight(filePath string) (string, error) {
+func (ctx *Context) ImageHeight(filePath string) (string, error) {
 	filePath = strings.Trim(filePath, "'\"")
 
 	// Check for external URLs
@@ -47,7 +100,7 @@ func ImageHeight(filePath string) (string, error) {
 		return "", fmt.Errorf("image-height: external URLs not yet supported (tried %s)", filePath)
 	}
 
-	_, height, err := getImageDimensions(filePath)
+	_, height, err := ctx.getImageDimensions(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -55,8 +108,13 @@ func ImageHeight(filePath string) (string, error) {
 	return fmt.Sprintf("%dpx", height), nil
 }
 
+// ImageHeight is Context.ImageHeight with the default settings
+func ImageHeight(filePath string) (string, error) {
+	return (*Context)(nil).ImageHeight(filePath)
+}
+
 // ImageSize returns both width and height as a space-separated string
-func ImageSize(filePath string) (string, error) {
+func (ctx *Context) ImageSize(filePath string) (string, error) {
 	filePath = strings.Trim(filePath, "'\"")
 
 	// Check for external URLs
@@ -64,7 +122,7 @@ func ImageSize(filePath string) (string, error) {
 		return "", fmt.Errorf("image-size: external URLs not yet supported (tried %s)", filePath)
 	}
 
-	width, height, err := getImageDimensions(filePath)
+	width, height, err := ctx.getImageDimensions(filePath)
 	if err != nil {
 		return "", err
 	}
@@ -72,24 +130,28 @@ func ImageSize(filePath string) (string, error) {
 	return fmt.Sprintf("%dpx %dpx", width, height), nil
 }
 
-// getImageDimensions reads an image file and returns its dimensions
-func getImageDimensions(filePath string) (int, int, error) {
+// ImageSize is Context.ImageSize with the default settings
+func ImageSize(filePath string) (string, error) {
+	return (*Context)(nil).ImageSize(filePath)
+}
+
+// getImageDimensions reads an image file and returns its dimensions.
+// Files on disk are cached by path, files from the FS of ctx are not.
+func (ctx *Context) getImageDimensions(filePath string) (int, int, error) {
+	// Resolve the file path relative to the base directory if it's not absolute
+	resolvedPath := ctx.resolvePath(filePath)
+	cached := ctx == nil || ctx.FS == nil
+
 	// Check cache first
 	imageDimMutex.RLock()
-	if dims, ok := imageDimCache[filePath]; ok {
+	if dims, ok := imageDimCache[resolvedPath]; ok && cached {
 		imageDimMutex.RUnlock()
 		return dims[0], dims[1], nil
 	}
 	imageDimMutex.RUnlock()
 
-	// Resolve the file path relative to BaseDir if it's not absolute
-	resolvedPath := filePath
-	if BaseDir != "" && !filepath.IsAbs(filePath) {
-		resolvedPath = filepath.Join(BaseDir, filePath)
-	}
-
 	// Try to open the file
-	file, err := os.Open(resolvedPath)
+	file, err := ctx.open(filePath)
 	if err != nil {
 		return 0, 0, fmt.Errorf("cannot open image file %s: %w", filePath, err)
 	}
@@ -102,9 +164,11 @@ func getImageDimensions(filePath string) (int, int, error) {
 	}
 
 	// Cache the result
-	imageDimMutex.Lock()
-	imageDimCache[filePath] = [2]int{config.Width, config.Height}
-	imageDimMutex.Unlock()
+	if cached {
+		imageDimMutex.Lock()
+		imageDimCache[resolvedPath] = [2]int{config.Width, config.Height}
+		imageDimMutex.Unlock()
+	}
 
 	return config.Width, config.Height, nil
 }
@@ -7,54 +7,110 @@ import (
 	"github.com/titpetric/lessgo/internal/strings"
 )
 
-// Ceil returns the smallest integer >= x
+// Ceil returns the smallest integer >= x. Lists are mapped element-wise,
+// ceil(1.2px 2.5px) gives 2px 3px.
+func (ctx *Context) Ceil(value string) string {
+	return ctx.mapNumbers(value, math.Ceil)
+}
+
+// Ceil is Context.Ceil with the default settings
 func Ceil(value string) string {
-	num := parseNumber(value)
-	result := math.Ceil(num)
-	return formatNumber(result, value)
+	return (*Context)(nil).Ceil(value)
 }
 
-// Floor returns the largest integer <= x
+// Floor returns the largest integer <= x, lists are mapped element-wise
+func (ctx *Context) Floor(value string) string {
+	return ctx.mapNumbers(value, math.Floor)
+}
+
+// Floor is Context.Floor with the default settings
 func Floor(value string) string {
-	num := parseNumber(value)
-	result := math.Floor(num)
-	return formatNumber(result, value)
+	return (*Context)(nil).Floor(value)
+}
+
+// Round returns the nearest integer, lists are mapped element-wise
+func (ctx *Context) Round(value string) string {
+	return ctx.mapNumbers(value, math.Round)
 }
 
-// Round returns the nearest integer
+// Round is Context.Round with the default settings
 func Round(value string) string {
-	num := parseNumber(value)
-	result := math.Round(num)
-	return formatNumber(result, value)
+	return (*Context)(nil).Round(value)
+}
+
+// RoundTo rounds to the given number of decimal places, round(1.67, 1)
+// gives 1.7. Lists are mapped element-wise.
+func (ctx *Context) RoundTo(value string, places int) string {
+	scale := math.Pow(10, float64(places))
+	return ctx.mapNumbers(value, func(num float64) float64 {
+		return math.Round(num*scale) / scale
+	})
+}
+
+// RoundTo is Context.RoundTo with the default settings
+func RoundTo(value string, places int) string {
+	return (*Context)(nil).RoundTo(value, places)
+}
+
+// Abs returns the absolute value, lists are mapped element-wise
+func (ctx *Context) Abs(value string) string {
+	return ctx.mapNumbers(value, math.Abs)
 }
 
-// Abs returns the absolute value
+// Abs is Context.Abs with the default settings
 func Abs(value string) string {
-	num := parseNumber(value)
-	result := math.Abs(num)
-	return formatNumber(result, value)
+	return (*Context)(nil).Abs(value)
+}
+
+// Sqrt returns the square root, lists are mapped element-wise
+func (ctx *Context) Sqrt(value string) string {
+	return ctx.mapNumbers(value, math.Sqrt)
 }
 
-// Sqrt returns the square root
+// Sqrt is Context.Sqrt with the default settings
 func Sqrt(value string) string {
-	num := parseNumber(value)
-	result := math.Sqrt(num)
-	return formatNumber(result, value)
+	return (*Context)(nil).Sqrt(value)
 }
 
-// Pow returns base to the power of exponent
-func Pow(base, exponent string) string {
+// mapNumbers applies fn to every number in value, keeping units. The value
+// may be a comma list of space lists, the result is joined with the same
+// separators. Other list items pass through.
+func (ctx *Context) mapNumbers(value string, fn func(float64) float64) string {
+	items := splitList(value, ',')
+	for i, item := range items {
+		fields := splitList(item, ' ')
+		for j, field := range fields {
+			if IsNumber(field) {
+				fields[j] = ctx.formatNumber(fn(parseNumber(field)), field)
+			}
+		}
+		items[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(items, ", ")
+}
+
+// Pow returns base to the power of exponent, keeping the unit of the base
+func (ctx *Context) Pow(base, exponent string) string {
 	b := parseNumber(base)
 	e := parseNumber(exponent)
 	result := math.Pow(b, e)
-	return formatNumber(result, base)
+	return ctx.formatNumber(result, base)
 }
 
-// Min returns the minimum of the provided values
-func Min(values ...string) string {
+// Pow is Context.Pow with the default settings
+func Pow(base, exponent string) string {
+	return (*Context)(nil).Pow(base, exponent)
+}
+
+// Min returns the minimum of the provided values.
+// Values with mixed units or expressions pass through as CSS min().
+func (ctx *Context) Min(values ...string) string {
 	if len(values) == 0 {
 		return "0"
 	}
+	if !comparableNumbers(values) {
+		return cssFunction("min", values)
+	}
 
 	min := math.MaxFloat64
 	minUnit := ""
@@ -67,14 +123,23 @@ func Min(values ...string) string {
 		}
 	}
 
-	return formatNumberWithUnit(min, minUnit)
+	return ctx.formatNumberWithUnit(min, minUnit)
 }
 
-// Max returns the maximum of the provided values
-func Max(values ...string) string {
+// Min is Context.Min with the default settings
+func Min(values ...string) string {
+	return (*Context)(nil).Min(values...)
+}
+
+// Max returns the maximum of the provided values.
+// Values with mixed units or expressions pass through as CSS max().
+func (ctx *Context) Max(values ...string) string {
 	if len(values) == 0 {
 		return "0"
 	}
+	if !comparableNumbers(values) {
+		return cssFunction("max", values)
+	}
 
 	max := -math.MaxFloat64
 	maxUnit := ""
@@ -87,19 +152,67 @@ func Max(values ...string) string {
 		}
 	}
 
-	return formatNumberWithUnit(max, maxUnit)
+	return ctx.formatNumberWithUnit(max, maxUnit)
+}
+
+// Max is Context.Max with the default settings
+func Max(values ...string) string {
+	return (*Context)(nil).Max(values...)
+}
+
+// Clamp always passes through as CSS clamp()
+func Clamp(values ...string) string {
+	return cssFunction("clamp", values)
+}
+
+// comparableNumbers reports whether all values are plain numbers sharing the same unit
+// (unitless numbers are compatible with any unit)
+func comparableNumbers(values []string) bool {
+	unit := ""
+	for _, val := range values {
+		val = strings.TrimSpace(val)
+		u := extractUnit(val)
+		if len(u) == len(val) || !isUnitIdent(u) {
+			return false
+		}
+		if u == "" {
+			continue
+		}
+		if unit != "" && u != unit {
+			return false
+		}
+		unit = u
+	}
+	return true
+}
+
+// isUnitIdent checks if a string is a valid unit suffix (letters or %)
+func isUnitIdent(unit string) bool {
+	if unit == "%" {
+		return true
+	}
+	for i := 0; i < len(unit); i++ {
+		if (unit[i] < 'a' || unit[i] > 'z') && (unit[i] < 'A' || unit[i] > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// cssFunction renders a native CSS function call verbatim
+func cssFunction(name string, values []string) string {
+	args := make([]string, len(values))
+	for i, val := range values {
+		args[i] = strings.TrimSpace(val)
+	}
+	return name + "(" + strings.Join(args, ", ") + ")"
 }
 
 // parseNumber extracts the numeric part from a value string
 func parseNumber(value string) float64 {
 	value = strings.TrimSpace(value)
 	// Remove unit suffix
-	i := 0
-	for i < len(value) && (value[i] == '-' || value[i] == '+' || (value[i] >= '0' && value[i] <= '9') || value[i] == '.') {
-		i++
-	}
-	numStr := value[:i]
-	num, _ := strconv.ParseFloat(numStr, 64)
+	num, _ := strconv.ParseFloat(value[:numberPrefixLen(value)], 64)
 	return num
 }
 
@@ -107,21 +220,60 @@ func parseNumber(value string) float64 {
 func extractUnit(value string) string {
 	value = strings.TrimSpace(value)
 	// Skip the number part
+	return strings.TrimSpace(value[numberPrefixLen(value):])
+}
+
+// numberPrefixLen returns the length of the leading number in value,
+// including sign, decimals and an exponent (1.5e3), but not a unit like "em"
+func numberPrefixLen(value string) int {
 	i := 0
-	for i < len(value) && (value[i] == '-' || value[i] == '+' || (value[i] >= '0' && value[i] <= '9') || value[i] == '.') {
+	if i < len(value) && (value[i] == '-' || value[i] == '+') {
 		i++
 	}
-	return strings.TrimSpace(value[i:])
+	for i < len(value) && ((value[i] >= '0' && value[i] <= '9') || value[i] == '.') {
+		i++
+	}
+	// Exponent: e or E followed by an optional sign and at least one digit
+	if i > 0 && i < len(value) && (value[i] == 'e' || value[i] == 'E') {
+		j := i + 1
+		if j < len(value) && (value[j] == '-' || value[j] == '+') {
+			j++
+		}
+		if j < len(value) && value[j] >= '0' && value[j] <= '9' {
+			for j < len(value) && value[j] >= '0' && value[j] <= '9' {
+				j++
+			}
+			i = j
+		}
+	}
+	return i
+}
+
+// DefaultPrecision is the number of decimal places numeric results are
+// rounded to, matching less.js.
+const DefaultPrecision = 8
+
+// RoundPrecision rounds num to the context's Precision decimal places
+func (ctx *Context) RoundPrecision(num float64) float64 {
+	scale := math.Pow(10, float64(ctx.precision()))
+	return math.Round(num*scale) / scale
+}
+
+// RoundPrecision is Context.RoundPrecision with the default settings
+func RoundPrecision(num float64) float64 {
+	return (*Context)(nil).RoundPrecision(num)
 }
 
 // formatNumber formats a number, preserving the unit from the original value
-func formatNumber(result float64, original string) string {
+func (ctx *Context) formatNumber(result float64, original string) string {
 	unit := extractUnit(original)
-	return formatNumberWithUnit(result, unit)
+	return ctx.formatNumberWithUnit(result, unit)
 }
 
 // formatNumberWithUnit formats a number with a unit
-func formatNumberWithUnit(num float64, unit string) string {
+func (ctx *Context) formatNumberWithUnit(num float64, unit string) string {
+	num = ctx.RoundPrecision(num)
+
 	// Handle integer representation if the result is a whole number
 	if num == math.Floor(num) && num >= -1e15 && num <= 1e15 {
 		if unit == "" {
@@ -140,65 +292,102 @@ func formatNumberWithUnit(num float64, unit string) string {
 	return result + unit
 }
 
-// Mod returns the remainder of a / b
-func Mod(a, b string) string {
+// Mod returns the remainder of a / b.
+// Like less.js, the result takes the sign and unit of the dividend, and
+// is NaN when b is zero, e.g. mod(5px, 0) gives NaNpx.
+func (ctx *Context) Mod(a, b string) string {
 	aNum := parseNumber(a)
 	bNum := parseNumber(b)
 
 	if bNum == 0 {
-		return "0" // Avoid division by zero
+		return "NaN" + extractUnit(a)
 	}
 
 	result := math.Mod(aNum, bNum)
-	return formatNumber(result, a)
+	return ctx.formatNumber(result, a)
+}
+
+// Mod is Context.Mod with the default settings
+func Mod(a, b string) string {
+	return (*Context)(nil).Mod(a, b)
 }
 
 // Sin returns the sine of a number (in radians)
-func Sin(value string) string {
+func (ctx *Context) Sin(value string) string {
 	num := parseNumber(value)
 	result := math.Sin(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Sin is Context.Sin with the default settings
+func Sin(value string) string {
+	return (*Context)(nil).Sin(value)
 }
 
 // Cos returns the cosine of a number (in radians)
-func Cos(value string) string {
+func (ctx *Context) Cos(value string) string {
 	num := parseNumber(value)
 	result := math.Cos(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Cos is Context.Cos with the default settings
+func Cos(value string) string {
+	return (*Context)(nil).Cos(value)
 }
 
 // Tan returns the tangent of a number (in radians)
-func Tan(value string) string {
+func (ctx *Context) Tan(value string) string {
 	num := parseNumber(value)
 	result := math.Tan(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Tan is Context.Tan with the default settings
+func Tan(value string) string {
+	return (*Context)(nil).Tan(value)
 }
 
 // Asin returns the arcsine of a number (in radians)
-func Asin(value string) string {
+func (ctx *Context) Asin(value string) string {
 	num := parseNumber(value)
 	result := math.Asin(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Asin is Context.Asin with the default settings
+func Asin(value string) string {
+	return (*Context)(nil).Asin(value)
 }
 
 // Acos returns the arccosine of a number (in radians)
-func Acos(value string) string {
+func (ctx *Context) Acos(value string) string {
 	num := parseNumber(value)
 	result := math.Acos(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Acos is Context.Acos with the default settings
+func Acos(value string) string {
+	return (*Context)(nil).Acos(value)
 }
 
 // Atan returns the arctangent of a number (in radians)
-func Atan(value string) string {
+func (ctx *Context) Atan(value string) string {
 	num := parseNumber(value)
 	result := math.Atan(num)
-	return formatNumber(result, value)
+	return ctx.formatNumber(result, value)
+}
+
+// Atan is Context.Atan with the default settings
+func Atan(value string) string {
+	return (*Context)(nil).Atan(value)
 }
 
 // Pi returns the value of pi
-func Pi() string {
-	// LESS limits pi() to 8 decimal places
-	rounded := math.Round(math.Pi*100000000) / 100000000
+func (ctx *Context) Pi() string {
+	// LESS limits pi() to 8 decimal places by default
+	rounded := ctx.RoundPrecision(math.Pi)
 	result := strconv.FormatFloat(rounded, 'f', -1, 64)
 	// Remove trailing zeros after decimal point
 	if strings.Contains(result, ".") {
@@ -208,16 +397,26 @@ func Pi() string {
 	return result
 }
 
+// Pi is Context.Pi with the default settings
+func Pi() string {
+	return (*Context)(nil).Pi()
+}
+
 // Percentage converts a decimal number to a percentage
-func Percentage(value string) string {
+func (ctx *Context) Percentage(value string) string {
 	num := parseNumber(value)
 	result := num * 100
-	return formatNumberWithUnit(result, "%")
+	return ctx.formatNumberWithUnit(result, "%")
+}
+
+// Percentage is Context.Percentage with the default settings
+func Percentage(value string) string {
+	return (*Context)(nil).Percentage(value)
 }
 
 // EvaluateExpression evaluates a mathematical expression with units
 // e.g., "10px * 2" -> "20px", "20px - 5px" -> "15px"
-func EvaluateExpression(expr string) string {
+func (ctx *Context) EvaluateExpression(expr string) string {
 	expr = strings.TrimSpace(expr)
 
 	// Simple parser for left operand, operator, right operand
@@ -230,7 +429,7 @@ func EvaluateExpression(expr string) string {
 			left := strings.TrimSpace(expr[:i])
 			op := string(expr[i])
 			right := strings.TrimSpace(expr[i+1:])
-			return evaluateBinaryOp(left, op, right)
+			return ctx.evaluateBinaryOp(left, op, right)
 		}
 	}
 
@@ -240,13 +439,18 @@ func EvaluateExpression(expr string) string {
 			left := strings.TrimSpace(expr[:i])
 			op := string(expr[i])
 			right := strings.TrimSpace(expr[i+1:])
-			return evaluateBinaryOp(left, op, right)
+			return ctx.evaluateBinaryOp(left, op, right)
 		}
 	}
 
 	return ""
 }
 
+// EvaluateExpression is Context.EvaluateExpression with the default settings
+func EvaluateExpression(expr string) string {
+	return (*Context)(nil).EvaluateExpression(expr)
+}
+
 // isPartOfNumber checks if the character at position i is part of a number (not an operator)
 func isPartOfNumber(expr string, i int) bool {
 	if i == 0 {
@@ -258,7 +462,7 @@ func isPartOfNumber(expr string, i int) bool {
 }
 
 // evaluateBinaryOp evaluates a binary operation (left op right)
-func evaluateBinaryOp(left, op, right string) string {
+func (ctx *Context) evaluateBinaryOp(left, op, right string) string {
 	leftNum := parseNumber(left)
 	rightNum := parseNumber(right)
 
@@ -295,5 +499,5 @@ func evaluateBinaryOp(left, op, right string) string {
 		return ""
 	}
 
-	return formatNumberWithUnit(result, unit)
+	return ctx.formatNumberWithUnit(result, unit)
 }
@@ -0,0 +1,92 @@
+package functions
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMathFunctionsPreserveUnit(t *testing.T) {
+	ctx := &Context{}
+	tests := []struct {
+		name     string
+		fn       func(string) string
+		input    string
+		expected string
+	}{
+		{"ceil px", ctx.Ceil, "2.4px", "3px"},
+		{"ceil em", ctx.Ceil, "1.1em", "2em"},
+		{"ceil percent", ctx.Ceil, "33.3%", "34%"},
+		{"ceil unitless", ctx.Ceil, "2.4", "3"},
+		{"floor px", ctx.Floor, "2.6px", "2px"},
+		{"floor em", ctx.Floor, "-1.5em", "-2em"},
+		{"floor percent", ctx.Floor, "66.6%", "66%"},
+		{"floor unitless", ctx.Floor, "2.6", "2"},
+		{"abs px", ctx.Abs, "-5px", "5px"},
+		{"abs em", ctx.Abs, "-0.5em", "0.5em"},
+		{"abs percent", ctx.Abs, "-25%", "25%"},
+		{"abs unitless", ctx.Abs, "-3", "3"},
+		{"round px", ctx.Round, "1.5px", "2px"},
+		{"sqrt px", ctx.Sqrt, "16px", "4px"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.fn(tt.input))
+		})
+	}
+}
+
+func TestMathFunctionsMapLists(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "1px 3px", ctx.Round("1.4px 2.6px"))
+	require.Equal(t, "1px, 3px", ctx.Round("1.4px, 2.6px"))
+	require.Equal(t, "2em 3em, 1%", ctx.Ceil("1.2em 2.1em, 0.5%"))
+	require.Equal(t, "1 2 auto", ctx.Floor("1.8 2.9 auto"))
+	require.Equal(t, "5px 0 3px", ctx.Abs("-5px 0 -3px"))
+	require.Equal(t, "1.7", ctx.RoundTo("1.67", 1))
+	require.Equal(t, "1.67px 2", ctx.RoundTo("1.666px 2", 2))
+}
+
+func TestModPow(t *testing.T) {
+	ctx := &Context{}
+	tests := []struct {
+		name     string
+		fn       func(string, string) string
+		a, b     string
+		expected string
+	}{
+		{"mod negative dividend", ctx.Mod, "-7", "3", "-1"},
+		{"mod negative divisor", ctx.Mod, "7", "-3", "1"},
+		{"mod fractional", ctx.Mod, "5.5px", "2", "1.5px"},
+		{"mod keeps dividend unit", ctx.Mod, "11px", "3", "2px"},
+		{"mod by zero", ctx.Mod, "7px", "0", "NaNpx"},
+		{"pow keeps base unit", ctx.Pow, "2px", "2", "4px"},
+		{"pow negative base", ctx.Pow, "-2", "3", "-8"},
+		{"pow fractional exponent", ctx.Pow, "4%", "0.5", "2%"},
+		{"pow fractional result", ctx.Pow, "2", "-1", "0.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, tt.fn(tt.a, tt.b))
+		})
+	}
+}
+
+func TestPrecision(t *testing.T) {
+	ctx := &Context{}
+
+	require.Equal(t, "3.14159265", ctx.Pi())
+	require.Equal(t, "0.33333333px", ctx.formatNumber(1.0/3, "1px"))
+
+	ctx.Precision = 5
+	require.Equal(t, "3.14159", ctx.Pi())
+	require.Equal(t, "0.33333px", ctx.formatNumber(1.0/3, "1px"))
+	require.Equal(t, "hsl(0, 33.33333%, 50%)", ctx.formatColor("hsl", &Color{170, 85, 85, 1}))
+
+	ctx.Precision = 2
+	require.Equal(t, "3.14", ctx.Pi())
+	require.Equal(t, "0.33px", ctx.formatNumber(1.0/3, "1px"))
+	require.Equal(t, "rgba(0, 0, 0, 0.33)", ctx.FormatRGB(0, 0, 0, 1.0/3, true))
+}
@@ -1,6 +1,7 @@
 package functions
 
 import (
+	"net/url"
 	"regexp"
 	"strconv"
 
@@ -9,7 +10,7 @@ import (
 
 var (
 	// Cache compiled regex for format string replacements
-	formatPlaceholderRegex = regexp.MustCompile(`%[sd]`)
+	formatPlaceholderRegex = regexp.MustCompile(`%%|%[sdaSDA]`)
 )
 
 // IsNumber checks if a value is a number (with optional unit)
@@ -81,8 +82,8 @@ func IsColor(value string) bool {
 		hex := strings.TrimPrefix(value, "#")
 		if len(hex) == 3 || len(hex) == 4 || len(hex) == 6 || len(hex) == 8 {
 			// Check if all characters are valid hex digits
-			for _, ch := range hex {
-				if !((ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')) {
+			for i := 0; i < len(hex); i++ {
+				if !isHexDigit(hex[i]) {
 					return false
 				}
 			}
@@ -101,40 +102,8 @@ func IsColor(value string) bool {
 	}
 
 	// Check for named colors (CSS color keywords)
-	namedColors := map[string]bool{
-		"red": true, "green": true, "blue": true, "yellow": true, "orange": true,
-		"purple": true, "pink": true, "cyan": true, "magenta": true, "white": true,
-		"black": true, "gray": true, "grey": true, "silver": true, "gold": true,
-		"maroon": true, "navy": true, "teal": true, "olive": true, "lime": true,
-		"aqua": true, "fuchsia": true, "indigo": true, "turquoise": true, "khaki": true,
-		"tomato": true, "coral": true, "salmon": true, "chocolate": true, "peru": true,
-		"wheat": true, "tan": true, "beige": true, "ivory": true, "bisque": true,
-		"aliceblue": true, "antiquewhite": true, "aquamarine": true, "azure": true,
-		"brown": true, "burlywood": true, "cadetblue": true, "chartreuse": true,
-		"darkblue": true, "darkcyan": true, "darkgray": true, "darkgreen": true,
-		"darkkhaki": true, "darkmagenta": true, "darkolivegreen": true, "darkorange": true,
-		"darkorchid": true, "darkred": true, "darksalmon": true, "darkseagreen": true,
-		"darkslateblue": true, "darkslategray": true, "darkturquoise": true, "darkviolet": true,
-		"deeppink": true, "deepskyblue": true, "dimgray": true, "dodgerblue": true,
-		"firebrick": true, "floralwhite": true, "forestgreen": true, "gainsboro": true,
-		"ghostwhite": true, "goldenrod": true, "honeydew": true, "hotpink": true,
-		"indianred": true, "lavender": true, "lavenderblush": true, "lawngreen": true,
-		"lemonchiffon": true, "lightblue": true, "lightcoral": true, "lightcyan": true,
-		"lightgoldenrodyellow": true, "lightgray": true, "lightgreen": true, "lightpink": true,
-		"lightsalmon": true, "lightseagreen": true, "lightskyblue": true, "lightslategray": true,
-		"lightsteelblue": true, "lightyellow": true, "mediumaquamarine": true, "mediumblue": true,
-		"mediumorchid": true, "mediumpurple": true, "mediumseagreen": true, "mediumslateblue": true,
-		"mediumspringgreen": true, "mediumturquoise": true, "mediumvioletred": true, "midnightblue": true,
-		"mintcream": true, "mistyrose": true, "moccasin": true, "navajowhite": true, "oldlace": true,
-		"olivedrab": true, "orangered": true, "orchid": true, "palegoldenrod": true, "palegreen": true,
-		"paleturquoise": true, "palevioletred": true, "papayawhip": true, "peachpuff": true,
-		"plum": true, "powderblue": true, "rosybrown": true, "royalblue": true, "saddlebrown": true,
-		"sandybrown": true, "seagreen": true, "seashell": true, "sienna": true, "skyblue": true,
-		"slateblue": true, "slategray": true, "snow": true, "springgreen": true, "steelblue": true,
-		"thistle": true, "violet": true, "whitesmoke": true, "yellowgreen": true,
-	}
-
-	return namedColors[value]
+	_, ok := cssColorKeywords[strings.ToLower(value)]
+	return ok
 }
 
 // IsKeyword checks if a value is a keyword
@@ -210,6 +179,11 @@ func IsUnit(value string, unit string) bool {
 	return actualUnit == unit
 }
 
+// IsUnitless checks if a value is a number without a unit
+func IsUnitless(value string) bool {
+	return IsNumber(value) && GetUnit(value) == ""
+}
+
 // IsRuleset checks if a value is a ruleset/object (stored in a variable)
 // This would need context from the renderer to properly determine
 func IsRuleset(value string) bool {
@@ -305,6 +279,14 @@ func IsUnitFunction(value string, unit string) string {
 	return "false"
 }
 
+// IsUnitlessFunction is the exposed function for isunitless()
+func IsUnitlessFunction(value string) string {
+	if IsUnitless(value) {
+		return "true"
+	}
+	return "false"
+}
+
 // IsRulesetFunction is the exposed function for isruleset()
 func IsRulesetFunction(value string) string {
 	if IsRuleset(value) {
@@ -362,27 +344,7 @@ func Length(values ...string) string {
 		return strconv.Itoa(len(values))
 	}
 
-	value := strings.TrimSpace(values[0])
-
-	// Quoted strings are single items, return 1
-	if IsString(value) {
-		return "1"
-	}
-
-	// Check if it's a comma-separated list
-	if strings.Contains(value, ",") {
-		items := strings.Split(value, ",")
-		return strconv.Itoa(len(items))
-	}
-
-	// Check if it's a space-separated list
-	items := strings.Fields(value)
-	if len(items) > 1 {
-		return strconv.Itoa(len(items))
-	}
-
-	// Single item
-	return "1"
+	return strconv.Itoa(len(listItems(values[0])))
 }
 
 // Extract gets an item from a list by index (1-based)
@@ -399,41 +361,98 @@ func Extract(args ...string) string {
 		return ""
 	}
 
-	var list string
-
-	// If multiple arguments (more than just list and index), join them as a list
-	if len(args) > 2 {
-		// Items are passed as separate arguments, they form a list
-		listItems := args[:len(args)-1]
-		if idx <= len(listItems) {
-			return strings.TrimSpace(listItems[idx-1])
-		}
+	// Items are passed as separate arguments, they form a list
+	items := args[:len(args)-1]
+	if len(items) == 1 {
+		items = listItems(items[0])
+	}
+	if idx > len(items) {
 		return ""
 	}
+	return strings.TrimSpace(items[idx-1])
+}
 
-	// Single argument before index - could be a list or single item
-	list = strings.TrimSpace(args[0])
+// listItems splits a list value into its items. Commas separate the outer
+// list and spaces the inner one, so "a b, c" has the items "a b" and "c",
+// while "a b c" has three items. Quoted strings and groups in parentheses
+// or brackets are single items, a list wrapped in them like (a, b) is
+// unwrapped first. Empty items between commas are kept, a blank value
+// has no items.
+func listItems(value string) []string {
+	value = unwrapList(strings.TrimSpace(value))
+	if value == "" {
+		return nil
+	}
+	if items := splitList(value, ','); len(items) > 1 {
+		for i, item := range items {
+			items[i] = strings.TrimSpace(item)
+		}
+		return items
+	}
+	return splitList(value, ' ')
+}
 
-	// Comma-separated list
-	if strings.Contains(list, ",") {
-		items := strings.Split(list, ",")
-		if len(items) >= idx {
-			return strings.TrimSpace(items[idx-1])
+// unwrapList removes parentheses or brackets enclosing the whole value
+func unwrapList(value string) string {
+	for len(value) >= 2 {
+		open, end := value[0], value[len(value)-1]
+		if !(open == '(' && end == ')' || open == '[' && end == ']') {
+			return value
 		}
-	} else {
-		// Space-separated list
-		items := strings.Fields(list)
-		if len(items) >= idx {
-			return items[idx-1]
+		if items := splitList(value, ' '); len(items) != 1 {
+			return value
 		}
+		value = strings.TrimSpace(value[1 : len(value)-1])
+	}
+	return value
+}
+
+// splitList splits value at separators outside quotes, parentheses and
+// brackets. A space separator matches any run of whitespace and doesn't
+// produce empty items.
+func splitList(value string, sep byte) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case depth == 0 && sep == ' ' && isSpaceByte(ch):
+			if i > start {
+				items = append(items, value[start:i])
+			}
+			start = i + 1
+		case depth == 0 && ch == sep:
+			items = append(items, value[start:i])
+			start = i + 1
+		}
+	}
+	if sep != ' ' || start < len(value) {
+		items = append(items, value[start:])
 	}
+	return items
+}
 
-	return ""
+// isSpaceByte checks if ch is whitespace
+func isSpaceByte(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
 }
 
 // Range generates a comma-separated list of numbers from start to end
 // Supports: range(end), range(start, end), range(start, end, step)
-func Range(args ...string) string {
+func (ctx *Context) Range(args ...string) string {
 	if len(args) == 0 {
 		return ""
 	}
@@ -486,77 +505,85 @@ func Range(args ...string) string {
 	result := make([]string, 0, resultLen)
 	if s <= e {
 		for i := s; i <= e; i += stepVal {
-			result = append(result, formatNumber(i, startTrimmed))
+			result = append(result, ctx.formatNumber(i, startTrimmed))
 		}
 	} else {
 		for i := s; i >= e; i -= stepVal {
-			result = append(result, formatNumber(i, startTrimmed))
+			result = append(result, ctx.formatNumber(i, startTrimmed))
 		}
 	}
 
 	return strings.Join(result, ", ")
 }
 
+// Range is Context.Range with the default settings
+func Range(args ...string) string {
+	return (*Context)(nil).Range(args...)
+}
+
 // Escape URL-encodes a string (using strict LESS escaping rules)
 // LESS escape() does NOT escape all special characters - only specific ones
 func Escape(str string) string {
-	// Remove quotes if present
-	str = strings.TrimSpace(str)
-	if len(str) >= 2 && ((str[0] == '"' && str[len(str)-1] == '"') ||
-		(str[0] == '\'' && str[len(str)-1] == '\'')) {
-		str = str[1 : len(str)-1]
-	}
-
-	// URL encode only specific characters (matching LESS behavior)
-	replacer := strings.NewReplacer(
-		" ", "%20",
-		"\"", "%22",
-		"#", "%23",
-		"$", "%24",
-		"%", "%25",
-		"&", "%26",
-		"'", "%27",
-		"(", "%28",
-		")", "%29",
-		"*", "%2A",
-		"+", "%2B",
-		",", "%2C",
-		"/", "%2F",
-		":", "%3A",
-		";", "%3B",
-		"<", "%3C",
-		"=", "%3D",
-		">", "%3E",
-		"?", "%3F",
-		"@", "%40",
-		"[", "%5B",
-		"\\", "%5C",
-		"]", "%5D",
-		"^", "%5E",
-		"`", "%60",
-		"{", "%7B",
-		"|", "%7C",
-		"}", "%7D",
-		"~", "%7E",
-	)
-
-	return replacer.Replace(str)
+	str = unquoteString(str)
+
+	// Encode like less.js: encodeURI plus = : # ; ( )
+	// Existing %XX escapes are kept so an encoded string isn't encoded twice
+	var buf strings.Builder
+	for i := 0; i < len(str); i++ {
+		ch := str[i]
+		switch {
+		case ch == '%' && i+2 < len(str) && isHexDigit(str[i+1]) && isHexDigit(str[i+2]):
+			buf.WriteByte(ch)
+		case ch >= 0x80 || strings.IndexByte(escapeChars, ch) >= 0:
+			buf.WriteByte('%')
+			buf.WriteByte(hexDigits[ch>>4])
+			buf.WriteByte(hexDigits[ch&0x0f])
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String()
 }
 
-// E returns the escaped string (similar to escape but used in LESS for removing quotes)
-func E(str string) string {
-	str = strings.TrimSpace(str)
+const (
+	// escapeChars lists the ASCII characters escape() encodes
+	escapeChars = " \"%<>[\\]^`{|}=:#;()"
 
-	// Remove quotes if present
-	if len(str) >= 2 && ((str[0] == '"' && str[len(str)-1] == '"') ||
-		(str[0] == '\'' && str[len(str)-1] == '\'')) {
-		str = str[1 : len(str)-1]
+	hexDigits = "0123456789ABCDEF"
+)
+
+// isHexDigit checks if a byte is a hexadecimal digit
+func isHexDigit(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+// unquoteString removes matching outer quotes from a single quoted string.
+// Keywords, numbers and values like "a" "b" are returned unchanged.
+func unquoteString(str string) string {
+	str = strings.TrimSpace(str)
+	if len(str) < 2 || (str[0] != '"' && str[0] != '\'') || str[len(str)-1] != str[0] {
+		return str
 	}
+	inner := str[1 : len(str)-1]
+	for i := 0; i < len(inner); i++ {
+		if inner[i] == str[0] && (i == 0 || inner[i-1] != '\\') {
+			return str
+		}
+	}
+	return inner
+}
 
-	return str
+// E returns the escaped string (similar to escape but used in LESS for removing quotes)
+func E(str string) string {
+	return unquoteString(str)
 }
 
-// Format string - simple % formatting similar to LESS
+// Format implements %("format", args...) like less.js. Placeholders are
+// replaced with the arguments in order: %s unquotes string arguments, %d
+// and %a keep them as given, quotes included. Uppercase %S, %D and %A URL
+// encode the value as less.js does, rather than quoting it; %d and %a
+// already keep the quotes of a string argument. Placeholders without an
+// argument are kept as is, and %% is a literal percent sign.
 func Format(format string, args ...string) string {
 	format = strings.TrimSpace(format)
 
@@ -566,36 +593,46 @@ func Format(format string, args ...string) string {
 		format = format[1 : len(format)-1]
 	}
 
-	// Replace %s and %d with arguments in order
 	argIdx := 0
 	result := formatPlaceholderRegex.ReplaceAllStringFunc(format, func(match string) string {
-		if argIdx < len(args) {
-			arg := args[argIdx]
-			argIdx++
-			// Remove quotes from argument if present
-			arg = strings.TrimSpace(arg)
-			if len(arg) >= 2 && ((arg[0] == '"' && arg[len(arg)-1] == '"') ||
-				(arg[0] == '\'' && arg[len(arg)-1] == '\'')) {
-				arg = arg[1 : len(arg)-1]
-			} else {
-				// If not quoted and contains operators, try to evaluate as an expression
-				if strings.ContainsAny(arg, "+-*/") {
-					// Try simple evaluation for numeric expressions
-					evaluated := tryEvaluateSimpleExpr(arg)
-					if evaluated != "" {
-						arg = evaluated
-					}
-				}
+		if match == "%%" {
+			return "%"
+		}
+		if argIdx >= len(args) {
+			return match
+		}
+		arg := strings.TrimSpace(args[argIdx])
+		argIdx++
+
+		unquoted := unquoteString(arg)
+		switch {
+		case unquoted != arg:
+			if match[1] == 's' || match[1] == 'S' {
+				arg = unquoted
+			}
+		case strings.ContainsAny(arg, "+-*/"):
+			// Try simple evaluation for numeric expressions
+			if evaluated := tryEvaluateSimpleExpr(arg); evaluated != "" {
+				arg = evaluated
 			}
-			return arg
 		}
-		return match
+
+		if match[1] >= 'A' && match[1] <= 'Z' {
+			return encodeURIComponent(arg)
+		}
+		return arg
 	})
 
 	// Wrap result in quotes as format returns a string
 	return `"` + result + `"`
 }
 
+// encodeURIComponent escapes s like the JavaScript function of the same name
+func encodeURIComponent(s string) string {
+	encoded := strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+	return strings.NewReplacer("%21", "!", "%27", "'", "%28", "(", "%29", ")", "%2A", "*").Replace(encoded)
+}
+
 // tryEvaluateSimpleExpr tries to evaluate a simple arithmetic expression
 func tryEvaluateSimpleExpr(expr string) string {
 	expr = strings.TrimSpace(expr)
@@ -642,9 +679,21 @@ func tryEvaluateSimpleExpr(expr string) string {
 	return ""
 }
 
+// Default implements default(). It's only meaningful in mixin guards and in
+// values inside the body of a mixin, e.g. `width: if(default(), auto, 50%)`.
+// Anywhere else it returns "false".
+func (ctx *Context) Default() string {
+	return strconv.FormatBool(ctx.defaultMatch())
+}
+
+// Default is Context.Default with the default settings
+func Default() string {
+	return (*Context)(nil).Default()
+}
+
 // If - logical conditional function
 // if(condition, value-if-true, value-if-false)
-func If(condition string, valueIfTrue string, valueIfFalse string) string {
+func (ctx *Context) If(condition string, valueIfTrue string, valueIfFalse string) string {
 	condition = strings.TrimSpace(condition)
 
 	// If condition is a function call or expression, evaluate it
@@ -674,6 +723,8 @@ func If(condition string, valueIfTrue string, valueIfFalse string) string {
 
 						// Handle type checking functions specially
 						switch funcName {
+						case "default":
+							condition = ctx.Default()
 						case "iscolor":
 							// Check if the argument is a color
 							_, err := ParseColor(funcBody)
@@ -710,6 +761,11 @@ func If(condition string, valueIfTrue string, valueIfFalse string) string {
 	return valueIfTrue
 }
 
+// If is Context.If with the default settings
+func If(condition string, valueIfTrue string, valueIfFalse string) string {
+	return (*Context)(nil).If(condition, valueIfTrue, valueIfFalse)
+}
+
 // evaluateExpressionSimple evaluates a simple expression string
 // Returns "true" or "false" for boolean expressions, "" if not a simple boolean
 // This is used by Boolean() to evaluate comparison expressions
@@ -727,6 +783,14 @@ func evaluateExpressionSimple(expr string) string {
 		return ""
 	}
 
+	// A single = compares values, colors by their resolved channels
+	if left, right, ok := splitEquality(expr); ok {
+		if valuesEqual(left, right) {
+			return "true"
+		}
+		return "false"
+	}
+
 	// Extract numeric values from operands with units
 	// Replace "14px > 12px" with "14 > 12"
 	processedExpr := preprocessComparisonExpr(expr)
@@ -737,6 +801,40 @@ func evaluateExpressionSimple(expr string) string {
 	return evaluateSimpleComparison(processedExpr)
 }
 
+// splitEquality splits expr around a single = that isn't part of <=, >=, != or ==
+func splitEquality(expr string) (left, right string, ok bool) {
+	for i := 0; i < len(expr); i++ {
+		if expr[i] != '=' {
+			continue
+		}
+		if i > 0 && strings.IndexByte("<>!=", expr[i-1]) != -1 {
+			continue
+		}
+		if i+1 < len(expr) && strings.IndexByte("<>=", expr[i+1]) != -1 {
+			continue
+		}
+		return strings.TrimSpace(expr[:i]), strings.TrimSpace(expr[i+1:]), true
+	}
+	return "", "", false
+}
+
+// valuesEqual compares two operands of an equality. Colors compare by
+// their RGBA value, so #fff equals white, numbers compare numerically
+// and anything else compares by text with outer quotes removed.
+func valuesEqual(left, right string) bool {
+	if IsColor(left) && IsColor(right) {
+		a, errA := ParseColor(strings.ToLower(left))
+		b, errB := ParseColor(strings.ToLower(right))
+		if errA == nil && errB == nil {
+			return a.ToHex() == b.ToHex()
+		}
+	}
+	if IsNumber(left) && IsNumber(right) {
+		return parseNumberWithUnits(left) == parseNumberWithUnits(right)
+	}
+	return unquoteString(left) == unquoteString(right)
+}
+
 // preprocessComparisonExpr removes units from numeric values in comparison expressions
 func preprocessComparisonExpr(expr string) string {
 	// Common units to remove from numbers before comparison
@@ -1 +1,158 @@
 package functions
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestE(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"alpha(opacity=50)"`, "alpha(opacity=50)"},
+		{`'single'`, "single"},
+		{"keyword", "keyword"},
+		{"10px", "10px"},
+		{`""`, ""},
+		{"", ""},
+		{`"'nested'"`, "'nested'"},
+		{`"a" "b"`, `"a" "b"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, E(tt.input))
+		})
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a b"`, "a%20b"},
+		{"a=1;b:2", "a%3D1%3Bb%3A2"},
+		{`"#id (x)"`, "%23id%20%28x%29"},
+		{"a%20b", "a%20b"},
+		{"100%", "100%25"},
+		{"/path/to?q&x", "/path/to?q&x"},
+		{`""`, ""},
+		{`"'q'"`, "'q'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.expected, Escape(tt.input))
+		})
+	}
+}
+
+func TestGetUnit(t *testing.T) {
+	tests := []struct {
+		input    string
+		unit     string
+		unitless bool
+	}{
+		{"10", "", true},
+		{"-2.5", "", true},
+		{"10px", "px", false},
+		{"50%", "%", false},
+		{"1.5em", "em", false},
+		{"1e3", "", true},
+		{"1.5e2px", "px", false},
+		{"2rem", "rem", false},
+		{"auto", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			require.Equal(t, tt.unit, GetUnit(tt.input))
+			require.Equal(t, tt.unitless, IsUnitless(tt.input))
+		})
+	}
+}
+
+func TestDefault(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "false", ctx.Default())
+	require.Equal(t, "50%", ctx.If("default()", "auto", "50%"))
+
+	ctx.DefaultMatch = true
+
+	require.Equal(t, "true", ctx.Default())
+	require.Equal(t, "auto", ctx.If("default()", "auto", "50%"))
+}
+
+func TestIfColorEquality(t *testing.T) {
+	ctx := &Context{}
+	require.Equal(t, "yes", ctx.If("(#fff = white)", "yes", "no"))
+	require.Equal(t, "yes", ctx.If("(rgb(255, 255, 255) = #FFFFFF)", "yes", "no"))
+	require.Equal(t, "yes", ctx.If("(rgba(0, 0, 0, 0.5) = #00000080)", "yes", "no"))
+	require.Equal(t, "no", ctx.If("(#eee = white)", "yes", "no"))
+	require.Equal(t, "no", ctx.If("(1 = 2)", "yes", "no"))
+	require.Equal(t, "yes", ctx.If("(10px = 10px)", "yes", "no"))
+}
+
+func TestFormat(t *testing.T) {
+	require.Equal(t, `"x-2"`, Format(`"%s-%d"`, `"x"`, "2"))
+	require.Equal(t, `"repetitions: 3 file: "directory/file.less""`, Format(`"repetitions: %d file: %d"`, "1 + 2", `"directory/file.less"`))
+	require.Equal(t, `"repetitions: 3 file: directory/file.less"`, Format(`"repetitions: %a file: %s"`, "1 + 2", `"directory/file.less"`))
+	require.Equal(t, `"100%"`, Format(`"100%%"`))
+
+	// Uppercase placeholders URL encode like less.js, %A keeps the quotes as %a does
+	require.Equal(t, `"a%20b %22c%22"`, Format(`"%S %A"`, `"a b"`, `"c"`))
+	require.Equal(t, `"url(%22a%20b%22)"`, Format(`"url(%A)"`, `"a b"`))
+
+	// Placeholders without an argument are kept, extra arguments are ignored
+	require.Equal(t, `"a %s %d"`, Format(`"%s %s %d"`, "a"))
+	require.Equal(t, `"a"`, Format(`"%s"`, "a", "b"))
+}
+
+func TestListItems(t *testing.T) {
+	tests := []struct {
+		value string
+		items []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{"a b c", []string{"a", "b", "c"}},
+		{"a  b\tc", []string{"a", "b", "c"}},
+		{"a, b, c", []string{"a", "b", "c"}},
+		{"a b, c", []string{"a b", "c"}},
+		{"1px solid red, 2px dashed blue", []string{"1px solid red", "2px dashed blue"}},
+		{`"a, b"`, []string{`"a, b"`}},
+		{`"a b" c`, []string{`"a b"`, "c"}},
+		{`"a" "b"`, []string{`"a"`, `"b"`}},
+		{"(a, b)", []string{"a", "b"}},
+		{"((a b))", []string{"a", "b"}},
+		{"[a b c]", []string{"a", "b", "c"}},
+		{"[a] [b]", []string{"[a]", "[b]"}},
+		{"rgb(1, 2, 3) blue", []string{"rgb(1, 2, 3)", "blue"}},
+		{"calc(1px + 2px), 3px", []string{"calc(1px + 2px)", "3px"}},
+		{"a, , b", []string{"a", "", "b"}},
+		{"a,", []string{"a", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			items := listItems(tt.value)
+			require.Equal(t, tt.items, items)
+			require.Equal(t, strconv.Itoa(len(tt.items)), Length(tt.value))
+			for i, item := range tt.items {
+				require.Equal(t, item, Extract(tt.value, strconv.Itoa(i+1)))
+			}
+			require.Equal(t, "", Extract(tt.value, strconv.Itoa(len(tt.items)+1)))
+		})
+	}
+}
+
+func TestExtractArguments(t *testing.T) {
+	require.Equal(t, "b", Extract("a", "b", "c", "2"))
+	require.Equal(t, "3", Length("a", "b c", "d"))
+	require.Equal(t, "", Extract("a b", "0"))
+	require.Equal(t, "", Extract("a b", "x"))
+}
@@ -4,29 +4,41 @@ import (
 	"fmt"
 	"html/template"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/titpetric/lessgo/expression/functions"
 )
 
+// function is a registered function, called with the context of the evaluator
+type function func(ctx *functions.Context, args ...any) (any, error)
+
 var funcMap template.FuncMap
+var functionMap map[string]function
 var cachedFunctionNames []string
 
+// contextType is the type of the optional first parameter of a registered function
+var contextType = reflect.TypeOf((*functions.Context)(nil))
+
 func init() {
 	funcMap = make(template.FuncMap)
+	functionMap = make(map[string]function)
 	registerFunctions()
 	// Pre-compute function names once
 	cachedFunctionNames = make([]string, 0, len(funcMap))
 	for k := range funcMap {
 		cachedFunctionNames = append(cachedFunctionNames, k)
 	}
+	sort.Strings(cachedFunctionNames)
 }
 
+// FuncMap returns the registered functions, evaluated with the default settings
 func FuncMap() template.FuncMap {
 	return funcMap
 }
 
-// GetRegisteredFunctionNames returns a list of all registered function names
+// GetRegisteredFunctionNames returns a sorted list of all registered function names
 func GetRegisteredFunctionNames() []string {
 	return cachedFunctionNames
 }
@@ -41,28 +53,34 @@ func registerFunctions() {
 	// register("multiply", functions.Multiply) // Conflicts with func_colors.go's Multiply
 	// register("divide", functions.Divide)
 
-	register("percentage", functions.Percentage)
+	register("percentage", (*functions.Context).Percentage)
 	register("rgb", functions.RGB)
-	register("hsl", functions.HSL)
-	register("hsla", functions.HSLA)
+	register("rgba", (*functions.Context).RGBA)
+	register("hsl", (*functions.Context).HSL)
+	register("hsla", (*functions.Context).HSLA)
 	register("hsv", functions.HSV)
-	register("hsva", functions.HSVA)
-	register("saturate", functions.Saturate)
-	register("desaturate", functions.Desaturate)
-	register("lighten", functions.Lighten)
-	register("darken", functions.Darken)
-	register("fadein", functions.Fadein)
-	register("fadeout", functions.Fadeout)
-	register("fade", functions.Fade)
-	register("spin", functions.Spin)
+	register("hsva", (*functions.Context).HSVA)
+	register("saturate", (*functions.Context).Saturate)
+	register("desaturate", (*functions.Context).Desaturate)
+	register("lighten", (*functions.Context).Lighten)
+	register("darken", (*functions.Context).Darken)
+	register("fadein", (*functions.Context).Fadein)
+	register("fadeout", (*functions.Context).Fadeout)
+	register("fade", (*functions.Context).Fade)
+	register("spin", (*functions.Context).Spin)
 	register("mix", functions.Mix)
 	register("hue", functions.Hue)
 	register("saturation", functions.Saturation)
 	register("lightness", functions.Lightness)
 	register("alpha", functions.Alpha)
-	register("luma", functions.LumaFunction)
-	register("luminance", functions.Luminance)
-	register("greyscale", functions.Greyscale)
+	// Setters aren't part of less.js, the set- prefix keeps them apart from CSS functions
+	register("set-hue", (*functions.Context).SetHue)
+	register("set-saturation", (*functions.Context).SetSaturation)
+	register("set-lightness", (*functions.Context).SetLightness)
+	register("set-alpha", (*functions.Context).SetAlpha)
+	register("luma", (*functions.Context).LumaFunction)
+	register("luminance", (*functions.Context).Luminance)
+	register("greyscale", (*functions.Context).Greyscale)
 	register("shade", functions.Shade)
 	register("tint", functions.Tint)
 	register("multiply", functions.Multiply)
@@ -89,29 +107,33 @@ func registerFunctions() {
 	register("isem", functions.IsEmFunction)
 	// register("isrem", functions.IsRem) // Not found in functions package
 	register("isunit", functions.IsUnitFunction)
+	register("isunitless", functions.IsUnitlessFunction)
+	register("is-unitless", functions.IsUnitlessFunction)
 	register("boolean", functions.Boolean)
-	register("round", functions.Round)
-	register("ceil", functions.Ceil)
-	register("floor", functions.Floor)
-	register("abs", functions.Abs)
-	register("min", functions.Min)
-	register("max", functions.Max)
-	register("sqrt", functions.Sqrt)
-	register("pow", functions.Pow)
-	register("mod", functions.Mod)
-	register("sin", functions.Sin)
-	register("cos", functions.Cos)
-	register("tan", functions.Tan)
-	register("asin", functions.Asin)
-	register("acos", functions.Acos)
-	register("atan", functions.Atan)
-	register("pi", functions.Pi)
+	register("round", round)
+	register("ceil", listArgs((*functions.Context).Ceil))
+	register("floor", listArgs((*functions.Context).Floor))
+	register("abs", listArgs((*functions.Context).Abs))
+	register("min", (*functions.Context).Min)
+	register("max", (*functions.Context).Max)
+	register("clamp", functions.Clamp)
+	register("sqrt", listArgs((*functions.Context).Sqrt))
+	register("pow", (*functions.Context).Pow)
+	register("mod", (*functions.Context).Mod)
+	register("sin", (*functions.Context).Sin)
+	register("cos", (*functions.Context).Cos)
+	register("tan", (*functions.Context).Tan)
+	register("asin", (*functions.Context).Asin)
+	register("acos", (*functions.Context).Acos)
+	register("atan", (*functions.Context).Atan)
+	register("pi", (*functions.Context).Pi)
 	register("escape", functions.Escape)
 	register("e", functions.E)
 	register("replace", functions.Replace)
 	register("format", functions.Format)
-	register("if", functions.If)
-	register("range", functions.Range)
+	register("if", (*functions.Context).If)
+	register("default", (*functions.Context).Default)
+	register("range", (*functions.Context).Range)
 	register("extract", functions.Extract)
 	register("unit", functions.Unit)
 	register("convert", functions.Convert)
@@ -123,8 +145,8 @@ func registerFunctions() {
 	register("colorfunction", functions.ColorFunction)
 	register("color", functions.ColorFunction) // alias
 	register("hsvhue", functions.HSVHue)
-	register("hsvsaturation", functions.HSVSaturation)
-	register("hsvvalue", functions.HSVValue)
+	register("hsvsaturation", (*functions.Context).HSVSaturation)
+	register("hsvvalue", (*functions.Context).HSVValue)
 	register("isdefined", functions.IsDefined)
 	register("islist", functions.IsList)
 	register("islistfunction", functions.IsListFunction)
@@ -141,13 +163,52 @@ func registerFunctions() {
 	register("isrulesetfunction", functions.IsRulesetFunction)
 
 	// Image functions
-	register("image-width", functions.ImageWidth)
-	register("image-height", functions.ImageHeight)
-	register("image-size", functions.ImageSize)
+	register("image-width", (*functions.Context).ImageWidth)
+	register("image-height", (*functions.Context).ImageHeight)
+	register("image-size", (*functions.Context).ImageSize)
+	register("data-uri", (*functions.Context).DataURI)
+}
+
+// listArgs adapts a function of a list to calls where a variable holding a
+// comma list spreads it over several arguments, e.g. ceil(@steps)
+func listArgs(fn func(*functions.Context, string) string) func(*functions.Context, ...string) string {
+	return func(ctx *functions.Context, values ...string) string {
+		return fn(ctx, strings.Join(values, ", "))
+	}
+}
+
+// round is round() with the optional number of decimal places to keep as a
+// unitless whole second argument, round(1.67, 1) gives 1.7
+func round(ctx *functions.Context, values ...string) string {
+	if len(values) == 2 {
+		if places, err := strconv.Atoi(strings.TrimSpace(values[1])); err == nil && places >= 0 {
+			return ctx.RoundTo(values[0], places)
+		}
+	}
+	return listArgs((*functions.Context).Round)(ctx, values...)
 }
 
+// ArgumentError is returned when a function is called with the wrong number
+// of arguments, e.g. percentage(1, 2)
+type ArgumentError struct {
+	Function string
+	Got      int
+	Want     int  // the number of arguments, or the minimum if Variadic
+	Variadic bool // the function takes any number of further arguments
+}
+
+func (e *ArgumentError) Error() string {
+	if e.Variadic {
+		return fmt.Sprintf("not enough arguments for %s", e.Function)
+	}
+	return fmt.Sprintf("wrong number of arguments for %s: got %d, want %d", e.Function, e.Got, e.Want)
+}
+
+// register adds a function. Methods of functions.Context are registered as
+// method expressions, e.g. (*functions.Context).Lighten, and are called
+// with the context the function is evaluated in.
 func register(name string, fn any) {
-	funcMap[name] = func(args ...any) (any, error) {
+	call := func(ctx *functions.Context, args ...any) (any, error) {
 		fnVal := reflect.ValueOf(fn)
 		fnType := fnVal.Type()
 
@@ -155,26 +216,34 @@ func register(name string, fn any) {
 			return nil, fmt.Errorf("not a function: %s", name)
 		}
 
+		// The context isn't one of the arguments
+		in := make([]reflect.Value, 0)
+		offset := 0
+		if fnType.NumIn() > 0 && fnType.In(0) == contextType {
+			in = append(in, reflect.ValueOf(ctx))
+			offset = 1
+		}
+		numIn := fnType.NumIn() - offset
+
 		// Check if the number of arguments is valid
 		if fnType.IsVariadic() {
-			if len(args) < fnType.NumIn()-1 {
-				return nil, fmt.Errorf("not enough arguments for %s", name)
+			if len(args) < numIn-1 {
+				return nil, &ArgumentError{Function: name, Got: len(args), Want: numIn - 1, Variadic: true}
 			}
 		} else {
-			if len(args) != fnType.NumIn() {
-				return nil, fmt.Errorf("wrong number of arguments for %s: got %d, want %d", name, len(args), fnType.NumIn())
+			if len(args) != numIn {
+				return nil, &ArgumentError{Function: name, Got: len(args), Want: numIn}
 			}
 		}
 
 		// Convert arguments to the required types
-		in := make([]reflect.Value, 0)
 		for i, arg := range args {
 			var targetType reflect.Type
-			if fnType.IsVariadic() && i >= fnType.NumIn()-1 {
+			if fnType.IsVariadic() && i >= numIn-1 {
 				// For variadic args, use the element type
 				targetType = fnType.In(fnType.NumIn() - 1).Elem()
 			} else {
-				targetType = fnType.In(i)
+				targetType = fnType.In(i + offset)
 			}
 			argVal := reflect.ValueOf(arg)
 			if argVal.Type().ConvertibleTo(targetType) {
@@ -210,12 +279,22 @@ func register(name string, fn any) {
 		}
 		return result, err
 	}
+	functionMap[name] = call
+	funcMap[name] = func(args ...any) (any, error) {
+		return call(nil, args...)
+	}
 }
 
+// Call calls a registered function with the default settings
 func Call(name string, args ...any) (any, error) {
+	return CallWithContext(nil, name, args...)
+}
+
+// CallWithContext calls a registered function with the settings of ctx
+func CallWithContext(ctx *functions.Context, name string, args ...any) (any, error) {
 	name = strings.ToLower(name)
-	if fn, ok := funcMap[name]; ok {
-		return fn.(func(...any) (any, error))(args...)
+	if fn, ok := functionMap[name]; ok {
+		return fn(ctx, args...)
 	}
 	return nil, fmt.Errorf("unknown function call: %s", name)
 }
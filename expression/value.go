@@ -5,6 +5,7 @@ import (
 	"regexp"
 	"strconv"
 
+	"github.com/titpetric/lessgo/expression/functions"
 	"github.com/titpetric/lessgo/internal/strings"
 )
 
@@ -20,6 +21,9 @@ type Value struct {
 	OriginalUnit string  // original unit before conversions (e.g., % before decimal conversion)
 	Color        *Color  // color value (for color values)
 	Raw          string  // original raw string (for debugging)
+
+	empty bool               // empty value, e.g. the result of get-unit(10)
+	ctx   *functions.Context // settings for arithmetic and formatting, set by the Evaluator
 }
 
 // NewValue creates a value from a number and unit
@@ -35,7 +39,7 @@ func NewValue(num float64, unit string) *Value {
 func NewColorValue(c *Color) *Value {
 	return &Value{
 		Color: c,
-		Raw:   c.String(),
+		Raw:   formatColor(nil, c, ""),
 	}
 }
 
@@ -44,6 +48,11 @@ func NewColorValue(c *Color) *Value {
 func Parse(s string) (*Value, error) {
 	s = strings.TrimSpace(s)
 
+	// Functions like get-unit(10) produce an empty value
+	if s == "" {
+		return &Value{empty: true}, nil
+	}
+
 	// Handle quoted strings
 	if (strings.HasPrefix(s, "\"") && strings.HasSuffix(s, "\"")) ||
 		(strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'")) {
@@ -53,10 +62,10 @@ func Parse(s string) (*Value, error) {
 	}
 
 	// Try to parse as color first (before bare keyword check)
-	if isColorLike(s) {
+	if functions.IsColor(s) {
 		c, err := ParseColor(s)
 		if err == nil {
-			return NewColorValue(c), nil
+			return &Value{Color: c, Raw: s}, nil
 		}
 		// If color parsing fails, fall through to other parsing
 	}
@@ -114,15 +123,6 @@ func isDigit(r rune) bool {
 	return r >= '0' && r <= '9'
 }
 
-// isColorLike checks if a string looks like a color
-func isColorLike(s string) bool {
-	return strings.HasPrefix(s, "#") ||
-		strings.HasPrefix(s, "rgb(") ||
-		strings.HasPrefix(s, "rgba(") ||
-		strings.HasPrefix(s, "hsl(") ||
-		strings.HasPrefix(s, "hsla(")
-}
-
 // String returns the string representation
 func (v *Value) String() string {
 	if v == nil {
@@ -130,7 +130,11 @@ func (v *Value) String() string {
 	}
 
 	if v.Color != nil {
-		return v.Color.String()
+		return formatColor(v.ctx, v.Color, v.Raw)
+	}
+
+	if v.empty {
+		return ""
 	}
 
 	// If Raw is set and Number is 0 (for lists and non-numeric values)
@@ -144,13 +148,9 @@ func (v *Value) String() string {
 		unit = v.OriginalUnit
 		// For percentage, reconstruct the percentage value from the decimal
 		if unit == "%" {
-			// Keep 8 decimal places for percentage values (matching lessc precision)
+			// Keep Precision decimal places for percentage values (matching lessc precision)
 			percentVal := v.Number * 100
-			formatted := fmt.Sprintf("%.8f", percentVal)
-			// Remove trailing zeros but keep integer format if possible
-			formatted = strings.TrimRight(formatted, "0")
-			formatted = strings.TrimRight(formatted, ".")
-			return formatted + "%"
+			return strconv.FormatFloat(v.ctx.RoundPrecision(percentVal), 'f', -1, 64) + "%"
 		}
 	}
 
@@ -181,7 +181,7 @@ func (v *Value) Add(other *Value) (*Value, error) {
 		unit = other.Unit
 	}
 
-	return NewValue(v.Number+other.Number, unit), nil
+	return v.result(v.Number+other.Number, unit), nil
 }
 
 // Subtract subtracts other from v
@@ -197,7 +197,7 @@ func (v *Value) Subtract(other *Value) (*Value, error) {
 		unit = other.Unit
 	}
 
-	return NewValue(v.Number-other.Number, unit), nil
+	return v.result(v.Number-other.Number, unit), nil
 }
 
 // Multiply multiplies two values
@@ -215,7 +215,7 @@ func (v *Value) Multiply(other *Value) (*Value, error) {
 		unit = other.Unit
 	}
 
-	return NewValue(v.Number*other.Number, unit), nil
+	return v.result(v.Number*other.Number, unit), nil
 }
 
 // Divide divides v by other
@@ -236,5 +236,12 @@ func (v *Value) Divide(other *Value) (*Value, error) {
 		return nil, fmt.Errorf("cannot divide %s by %s", v.Unit, other.Unit)
 	}
 
-	return NewValue(v.Number/other.Number, unit), nil
+	return v.result(v.Number/other.Number, unit), nil
+}
+
+// result creates the value of an operation on v, rounded to the precision of v
+func (v *Value) result(num float64, unit string) *Value {
+	result := NewValue(v.ctx.RoundPrecision(num), unit)
+	result.ctx = v.ctx
+	return result
 }
@@ -5,7 +5,6 @@ import (
 	"io/fs"
 	"net/http"
 
-	"github.com/titpetric/lessgo/dst"
 	"github.com/titpetric/lessgo/internal/strings"
 	"github.com/titpetric/lessgo/renderer"
 )
@@ -73,23 +72,15 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	var astFile *dst.File
-
 	// Parse the LESS file using configured parser
-	if dst.DefaultParserConfig.UseNoAlloc {
-		parser := dst.NewParserNoAllocWithFS(file, h.fileSystem)
-		astFile, err = parser.Parse()
-	} else {
-		parser := dst.NewParserWithFS(file, h.fileSystem)
-		astFile, err = parser.Parse()
-	}
+	astFile, err := Parse(file, h.fileSystem)
 	if err != nil {
 		http.Error(w, "Compilation Error", http.StatusInternalServerError)
 		return
 	}
 
-	// Render to CSS
-	cssRenderer := renderer.NewRenderer()
+	// Render to CSS, data-uri() and the image functions read from the same files
+	cssRenderer := renderer.NewRendererWithOptions(renderer.Options{FS: h.fileSystem})
 	css, err := cssRenderer.Render(astFile)
 	if err != nil {
 		http.Error(w, "Compilation Error", http.StatusInternalServerError)
@@ -141,6 +141,12 @@ var (
 	// Index returns the index of the first instance of substr in s, or -1 if substr is not present in s.
 	Index = stdstrings.Index
 
+	// IndexByte returns the index of the first instance of c in s, or -1 if c is not present in s.
+	IndexByte = stdstrings.IndexByte
+
+	// IndexAny returns the index of the first instance of any Unicode code point from chars in s, or -1 if no Unicode code point from chars is present in s.
+	IndexAny = stdstrings.IndexAny
+
 	// LastIndex returns the index of the last instance of substr in s, or -1 if substr is not present in s.
 	LastIndex = stdstrings.LastIndex
 
@@ -156,6 +162,15 @@ var (
 	// Trim returns a slice of the string s with all leading and trailing Unicode code points contained in cutset removed.
 	Trim = stdstrings.Trim
 
+	// Cut slices s around the first instance of sep, returning the text before and after sep. The found result reports whether sep appears in s.
+	Cut = stdstrings.Cut
+
+	// CutPrefix returns s without the provided leading prefix string and reports whether it found the prefix. If s doesn't start with prefix, CutPrefix returns s, false.
+	CutPrefix = stdstrings.CutPrefix
+
+	// CutSuffix returns s without the provided ending suffix string and reports whether it found the suffix. If s doesn't end with suffix, CutSuffix returns s, false.
+	CutSuffix = stdstrings.CutSuffix
+
 	// Split slices s into all substrings separated by sep and returns a slice of the substrings between those separators.
 	Split = stdstrings.Split
 
@@ -177,6 +192,9 @@ var (
 	// ToLower returns s with all Unicode letters mapped to their lower case.
 	ToLower = stdstrings.ToLower
 
+	// EqualFold reports whether s and t, interpreted as UTF-8 strings, are equal under simple Unicode case-folding, which is a more general form of case-insensitivity.
+	EqualFold = stdstrings.EqualFold
+
 	// Count counts the number of non-overlapping instances of substr in s. If substr is an empty string, Count returns 1 + the number of Unicode code points in s.
 	Count = stdstrings.Count
 
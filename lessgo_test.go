@@ -74,6 +74,20 @@ func testFixture(t *testing.T, fixturePath string) {
 	if diff != "" {
 		t.Error(diff)
 	}
+
+	// Fixtures don't repeat rules in @media blocks, so deduplicating them
+	// leaves the output as it is
+	dedupedCSS, err := renderer.NewRendererWithOptions(renderer.Options{DedupeMedia: true}).RenderWithBaseDir(astFile, dir)
+	require.NoError(t, err)
+	require.Equal(t, lessgoCSS, dedupedCSS)
+
+	// An inline source map is appended without changing the output
+	mappedCSS, err := renderer.NewRendererWithOptions(renderer.Options{SourceMapInline: true}).RenderWithBaseDir(astFile, dir)
+	require.NoError(t, err)
+	css, comment, ok := strings.Cut(mappedCSS, "/*# sourceMappingURL=data:application/json;base64,")
+	require.True(t, ok)
+	require.Equal(t, lessgoCSS, css)
+	require.True(t, strings.HasSuffix(comment, " */\n"))
 }
 
 // readExpectedCSS reads the expected CSS from the .css file adjacent to the .less file
@@ -284,6 +284,29 @@ func TestMiddlewareNestedDirectory(t *testing.T) {
 	require.Contains(t, css, ".btn")
 }
 
+// TestMiddlewareDataURI tests data-uri() reading files from the served file system
+func TestMiddlewareDataURI(t *testing.T) {
+	mockFS := fstest.MapFS{
+		"img/dot.png": &fstest.MapFile{Data: []byte("abc")},
+		"style.less":  &fstest.MapFile{Data: []byte(".a {\n  background: data-uri(\"img/dot.png\");\n}\n")},
+	}
+
+	middleware := NewMiddleware(mockFS, "/css")
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	handler := middleware(next)
+
+	req := httptest.NewRequest(http.MethodGet, "/css/style.less", nil)
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, ".a {\n  background: url(\"data:image/png;base64,YWJj\");\n}\n", w.Body.String())
+}
+
 // BenchmarkMiddleware benchmarks the middleware compilation and serving of LESS files
 func BenchmarkMiddleware(b *testing.B) {
 	lessContent, err := readFixture("testdata/fixtures/999-docker-ljubljana-index.less")
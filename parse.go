@@ -0,0 +1,18 @@
+package lessgo
+
+import (
+	"io"
+	"io/fs"
+
+	"github.com/titpetric/lessgo/dst"
+)
+
+// Parse parses LESS source into a *dst.File using the configured parser.
+// Imports are resolved against fileSystem. The returned file can be
+// rendered multiple times, e.g. with renderer.RenderWithVars.
+func Parse(r io.Reader, fileSystem fs.FS) (*dst.File, error) {
+	if dst.DefaultParserConfig.UseNoAlloc {
+		return dst.NewParserNoAllocWithFS(r, fileSystem).Parse()
+	}
+	return dst.NewParserWithFS(r, fileSystem).Parse()
+}
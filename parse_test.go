@@ -0,0 +1,27 @@
+package lessgo
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/internal/strings"
+	"github.com/titpetric/lessgo/renderer"
+)
+
+func TestParseRenderWithVars(t *testing.T) {
+	src := "@bg: white;\n@fg: black;\n.page {\n  background: @bg;\n  color: @fg;\n}\n"
+
+	file, err := Parse(strings.NewReader(src), os.DirFS("."))
+	require.NoError(t, err)
+
+	r := renderer.NewRenderer()
+
+	css, err := r.RenderWithVars(file, "", nil)
+	require.NoError(t, err)
+	require.Equal(t, ".page {\n  background: white;\n  color: black;\n}\n", css)
+
+	css, err = r.RenderWithVars(file, "", map[string]string{"bg": "#111", "@fg": "#eee"})
+	require.NoError(t, err)
+	require.Equal(t, ".page {\n  background: #111;\n  color: #eee;\n}\n", css)
+}
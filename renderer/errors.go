@@ -0,0 +1,20 @@
+package renderer
+
+import "github.com/titpetric/lessgo/internal/strings"
+
+// Errors holds the errors collected while rendering with Options.CollectErrors
+type Errors []error
+
+// Error joins the collected errors, one per line
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// Unwrap returns the collected errors for errors.Is and errors.As
+func (e Errors) Unwrap() []error {
+	return e
+}
@@ -0,0 +1,72 @@
+package renderer
+
+import "io/fs"
+
+// Options configures optional renderer behaviour
+type Options struct {
+	// PostProcess is called with the rendered CSS before it is returned.
+	// It can be used to pipe output through external tooling (autoprefixer, minifiers).
+	PostProcess func(css string) (string, error)
+
+	// ModernColors emits computed colors in the space separated rgb()/hsl()
+	// syntax with a slash alpha, e.g. rgb(255 0 0 / 50%), instead of rgba().
+	ModernColors bool
+
+	// Precision is the number of decimal places numeric results are rounded
+	// to before trailing zeros are trimmed. Zero uses functions.DefaultPrecision.
+	Precision int
+
+	// OutputStyle lays out the rendered CSS as expanded (the default),
+	// compact (one rule per line) or compressed (minified).
+	OutputStyle OutputStyle
+
+	// WarnRedefine is called with the name of a variable that is defined again
+	// in the same scope. The last definition wins; overriding a variable from
+	// an imported file or with !default isn't reported.
+	WarnRedefine func(name string)
+
+	// CollectErrors keeps rendering past errors instead of returning the first
+	// one. Failed declarations are written as given, and all errors, like
+	// undefined variables and mixins, failing guards and functions called with
+	// the wrong number of arguments, are returned together as Errors. Errors
+	// are prefixed with the source line when it's known, e.g. "line 3: ".
+	CollectErrors bool
+
+	// StripZeroUnits writes zero lengths and percentages as a bare 0, e.g.
+	// margin: 0px 0% becomes margin: 0. Times like 0s keep their unit, and so
+	// do properties where a unitless 0 means something else, like flex-basis.
+	StripZeroUnits bool
+
+	// DedupeMedia merges adjacent @media blocks with the same condition and
+	// removes repeated identical rules from them, e.g. when several mixin
+	// calls generate the same responsive rule.
+	DedupeMedia bool
+
+	// DataURISizeLimit is the size in bytes of the largest file data-uri()
+	// inlines, larger files are referenced with url(). Zero uses the 32KB
+	// functions.DefaultDataURISizeLimit, a negative value disables the check.
+	DataURISizeLimit int
+
+	// FS holds the files read by data-uri() and image-size(), image-width()
+	// and image-height(), with paths relative to the rendered file. When
+	// it's nil, files are read from disk relative to the base directory.
+	FS fs.FS
+
+	// OmitFinalNewline leaves out the newline ending the output. Trailing
+	// whitespace is always removed, and output otherwise ends with one newline.
+	OmitFinalNewline bool
+
+	// IncludeImportComments keeps all comments from imported files. By default
+	// only /*! ... */ comments are kept from imports, like license headers,
+	// while comments in the file being rendered are always kept.
+	IncludeImportComments bool
+
+	// SourceMapInline appends a source map to the output, as a base64 encoded
+	// sourceMappingURL comment. Rules and declarations are mapped to their
+	// lines in the rendered file, rules from imported files aren't mapped.
+	SourceMapInline bool
+
+	// SourceMapFile is the name of the rendered file in the source map,
+	// "input.less" when empty.
+	SourceMapFile string
+}
@@ -0,0 +1,332 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+func TestOptionsPostProcess(t *testing.T) {
+	file, err := dst.NewParser(strings.NewReader(".a { color: red; }")).Parse()
+	require.NoError(t, err)
+
+	r := NewRendererWithOptions(Options{
+		PostProcess: func(css string) (string, error) {
+			return "/* processed */\n" + css, nil
+		},
+	})
+	css, err := r.Render(file)
+	require.NoError(t, err)
+	require.Equal(t, "/* processed */\n.a {\n  color: red;\n}\n", css)
+
+	r = NewRendererWithOptions(Options{
+		PostProcess: func(css string) (string, error) {
+			return "", errors.New("boom")
+		},
+	})
+	_, err = r.Render(file)
+	require.Error(t, err)
+}
+
+func TestOptionsModernColors(t *testing.T) {
+	file, err := dst.NewParser(strings.NewReader(".a { color: fadeout(rgba(0, 0, 0, 0.8), 30%); }")).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRendererWithOptions(Options{ModernColors: true}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  color: rgb(0 0 0 / 50%);\n}\n", css)
+
+	css, err = NewRenderer().Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  color: rgba(0, 0, 0, 0.5);\n}\n", css)
+}
+
+func TestOptionsPrecision(t *testing.T) {
+	file, err := dst.NewParser(strings.NewReader(".a { width: (10px / 3); height: percentage(0.123456789); }")).Parse()
+	require.NoError(t, err)
+
+	tests := []struct {
+		precision int
+		want      string
+	}{
+		{0, ".a {\n  width: 3.33333333px;\n  height: 12.3456789%;\n}\n"},
+		{5, ".a {\n  width: 3.33333px;\n  height: 12.34568%;\n}\n"},
+		{2, ".a {\n  width: 3.33px;\n  height: 12.35%;\n}\n"},
+	}
+
+	for _, tt := range tests {
+		css, err := NewRendererWithOptions(Options{Precision: tt.precision}).Render(file)
+		require.NoError(t, err)
+		require.Equal(t, tt.want, css)
+	}
+}
+
+func TestOptionsConcurrentRenders(t *testing.T) {
+	file, err := dst.NewParser(strings.NewReader(".a { width: (10px / 3); color: fadeout(#000, 50%); }")).Parse()
+	require.NoError(t, err)
+
+	// Each render uses its own options, renders don't change each other's output
+	tests := []struct {
+		options Options
+		want    string
+	}{
+		{Options{}, ".a {\n  width: 3.33333333px;\n  color: rgba(0, 0, 0, 0.5);\n}\n"},
+		{Options{Precision: 2, ModernColors: true}, ".a {\n  width: 3.33px;\n  color: rgb(0 0 0 / 50%);\n}\n"},
+	}
+
+	results := make([]string, 8*len(tests))
+	errs := make([]error, len(results))
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = NewRendererWithOptions(tests[i%len(tests)].options).Render(file)
+		}()
+	}
+	wg.Wait()
+
+	for i, css := range results {
+		require.NoError(t, errs[i])
+		require.Equal(t, tests[i%len(tests)].want, css)
+	}
+}
+
+func TestOptionsWarnRedefine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"theme.less": {Data: []byte("@color: red;\n@size: 1px;\n")},
+	}
+	input := `@import "theme.less";
+@color: blue;
+@gap: 1px;
+.a { color: @color; gap: @gap; @inner: 1; @inner: 2; z-index: @inner; }
+@gap: 2px;
+@gap: 3px !default;
+`
+	file, err := dst.NewParserWithFS(strings.NewReader(input), fsys).Parse()
+	require.NoError(t, err)
+
+	var warnings []string
+	css, err := NewRendererWithOptions(Options{
+		WarnRedefine: func(name string) {
+			warnings = append(warnings, name)
+		},
+	}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  color: blue;\n  gap: 2px;\n  z-index: 2;\n}\n", css)
+	require.Equal(t, []string{"gap", "inner"}, warnings)
+}
+
+func TestOptionsOutputStyle(t *testing.T) {
+	input, err := os.ReadFile("../testdata/output-style/input.less")
+	require.NoError(t, err)
+
+	file, err := dst.NewParser(strings.NewReader(string(input))).Parse()
+	require.NoError(t, err)
+
+	for _, style := range []OutputStyle{OutputExpanded, OutputCompact, OutputCompressed} {
+		t.Run(string(style), func(t *testing.T) {
+			want, err := os.ReadFile("../testdata/output-style/" + string(style) + ".css")
+			require.NoError(t, err)
+
+			css, err := NewRendererWithOptions(Options{OutputStyle: style}).Render(file)
+			require.NoError(t, err)
+			require.Equal(t, string(want), css)
+		})
+	}
+
+	_, err = ParseOutputStyle("minified")
+	require.Error(t, err)
+}
+
+func TestOptionsCollectErrors(t *testing.T) {
+	input := ".a {\n  @gap: 1px + 2em;\n  height: 1px + 2em;\n  color: @missing;\n  width: 1px + 2em;\n  background: url(img@2x.png);\n  content: ~\"@not\";\n}\n" +
+		".m() when (@x > ) {\n  a: b;\n}\n.b {\n  .m();\n  .nope();\n  top: percentage(foo, bar);\n  color: red;\n}\n@media @bp {\n  .c { left: 0; }\n}\n"
+	file, err := dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	_, err = NewRenderer().Render(file)
+	require.EqualError(t, err, "line 2: @gap: cannot add px and em")
+
+	css, err := NewRendererWithOptions(Options{CollectErrors: true}).Render(file)
+	require.Equal(t, ".a {\n  height: 1px + 2em;\n  color: @missing;\n  width: 1px + 2em;\n  background: url(img@2x.png);\n  content: @not;\n}\n"+
+		".b {\n  top: percentage(foo, bar);\n  color: red;\n}\n@media @bp {\n  .c {\n    left: 0;\n  }\n}\n", css)
+
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	require.Len(t, errs, 8)
+	require.EqualError(t, errs[0], "line 2: @gap: cannot add px and em")
+	require.EqualError(t, errs[1], "line 3: height: cannot add px and em")
+	require.EqualError(t, errs[2], "line 4: color: undefined variable @missing")
+	require.EqualError(t, errs[3], "line 5: width: cannot add px and em")
+	require.ErrorContains(t, errs[4], `line 9: guard "(@x > )"`)
+	require.EqualError(t, errs[5], "line 14: undefined mixin .nope")
+	require.EqualError(t, errs[6], "line 15: top: wrong number of arguments for percentage: got 2, want 1")
+	require.EqualError(t, errs[7], "line 18: @media: undefined variable @bp")
+
+	// Errors are detected the same way without collecting them
+	for _, tc := range []struct{ input, err string }{
+		{".a { color: @missing; }", "line 1: color: undefined variable @missing"},
+		{".a { .nope(); }", "line 1: undefined mixin .nope"},
+		{".a { top: percentage(foo, bar); }", "line 1: top: wrong number of arguments for percentage: got 2, want 1"},
+		{"@media @bp { .a { top: 0; } }", "line 1: @media: undefined variable @bp"},
+	} {
+		file, err := dst.NewParser(strings.NewReader(tc.input)).Parse()
+		require.NoError(t, err)
+		_, err = NewRenderer().Render(file)
+		require.EqualError(t, err, tc.err, tc.input)
+	}
+}
+
+func TestOptionsStripZeroUnits(t *testing.T) {
+	input := ".a { margin: 0px 0% 10px -0.0em; padding: 0px; transition: opacity 0s linear 0ms; transition-delay: 0s; flex: 1 1 0%; color: hsl(0, 0%, 50%); width: calc(0px + 10%); --gap: 0px; top: ~\"0px\"; left: e(\"0%\"); content: \"a 0px b\" '0% c'; background: url(img/0px.png) 0px; }"
+	file, err := dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRendererWithOptions(Options{StripZeroUnits: true}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  margin: 0 0 10px 0;\n  padding: 0;\n  transition: opacity 0s linear 0ms;\n  transition-delay: 0s;\n"+
+		"  flex: 1 1 0%;\n  color: hsl(0, 0%, 50%);\n  width: calc(0px + 10%);\n  --gap: 0px;\n  top: 0px;\n  left: 0%;\n"+
+		"  content: \"a 0px b\" '0% c';\n  background: url(img/0px.png) 0;\n}\n", css)
+
+	css, err = NewRenderer().Render(file)
+	require.NoError(t, err)
+	require.Contains(t, css, "margin: 0px 0% 10px -0.0em;")
+}
+
+func TestOptionsDedupeMedia(t *testing.T) {
+	input := ".r() {\n  @media (max-width: 600px) {\n    .x { display: none; content: \"a   b\"; }\n  }\n}\n.a {\n  color: red;\n  .r();\n  .r();\n}\n.b {\n  color: blue;\n  .r();\n}\n"
+	file, err := dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRenderer().Render(file)
+	require.NoError(t, err)
+	require.Equal(t, 3, strings.Count(css, "@media"))
+
+	css, err = NewRendererWithOptions(Options{DedupeMedia: true}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  color: red;\n}\n@media (max-width: 600px) {\n  .a .x {\n    display: none;\n    content: \"a   b\";\n  }\n}\n"+
+		".b {\n  color: blue;\n}\n@media (max-width: 600px) {\n  .b .x {\n    display: none;\n    content: \"a   b\";\n  }\n}\n", css)
+
+	css, err = NewRendererWithOptions(Options{DedupeMedia: true, OutputStyle: OutputCompressed}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ".a{color:red}@media (max-width: 600px){.a .x{display:none;content:\"a   b\"}}.b{color:blue}@media (max-width: 600px){.b .x{display:none;content:\"a   b\"}}\n", css)
+
+	// Rules outside merged @media blocks are written as rendered, and
+	// comments keep their place between declarations
+	input = ":root {\n  --set: { a: b };\n}\n.c {\n  top: 0;\n  /* between */\n  left: 0;\n}\n" +
+		".r() {\n  @media print {\n    .y {\n      top: 0;\n      /* inside */\n      left: 0;\n    }\n  }\n}\n.d {\n  color: red;\n  .r();\n  .r();\n}\n"
+	file, err = dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	css, err = NewRendererWithOptions(Options{DedupeMedia: true}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, ":root {\n  --set: { a: b };\n}\n.c {\n  top: 0;\n  /* between */\n  left: 0;\n}\n.d {\n  color: red;\n}\n"+
+		"@media print {\n  .d .y {\n    top: 0;\n    /* inside */\n    left: 0;\n  }\n}\n", css)
+
+	css, err = NewRendererWithOptions(Options{DedupeMedia: true, OutputStyle: OutputCompact}).Render(file)
+	require.NoError(t, err)
+	require.Contains(t, css, ".c {\n  top: 0;\n  /* between */\n  left: 0;\n}\n")
+}
+
+func TestOptionsDataURISizeLimit(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "small.png"), []byte("abc"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "large.png"), make([]byte, 40*1024), 0o644))
+
+	input := `.a { small: data-uri("small.png"); large: data-uri("large.png"); }`
+	file, err := dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRenderer().RenderWithBaseDir(file, dir)
+	require.NoError(t, err)
+	require.Equal(t, ".a {\n  small: url(\"data:image/png;base64,YWJj\");\n  large: url(\"large.png\");\n}\n", css)
+
+	css, err = NewRendererWithOptions(Options{DataURISizeLimit: 2}).RenderWithBaseDir(file, dir)
+	require.NoError(t, err)
+	require.Contains(t, css, `small: url("small.png");`)
+
+	css, err = NewRendererWithOptions(Options{DataURISizeLimit: -1}).RenderWithBaseDir(file, dir)
+	require.NoError(t, err)
+	require.Contains(t, css, `large: url("data:image/png;base64,AAAA`)
+}
+
+func TestOptionsOmitFinalNewline(t *testing.T) {
+	file, err := dst.NewParser(strings.NewReader("/* note */\n.a { color: red; }\n\n\n")).Parse()
+	require.NoError(t, err)
+
+	for _, style := range []OutputStyle{OutputExpanded, OutputCompact, OutputCompressed} {
+		css, err := NewRendererWithOptions(Options{OutputStyle: style}).Render(file)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(css, "}\n"), style)
+		for _, line := range strings.Split(css, "\n") {
+			require.Equal(t, strings.TrimRight(line, " \t"), line, style)
+		}
+
+		css, err = NewRendererWithOptions(Options{OutputStyle: style, OmitFinalNewline: true}).Render(file)
+		require.NoError(t, err)
+		require.True(t, strings.HasSuffix(css, "}"), style)
+	}
+
+	require.Equal(t, ".a {\n  color: red;\n}\n", trimWhitespace(".a {  \n  color: red;\t\n}\n\n  \n", false))
+	require.Equal(t, "", trimWhitespace("\n\n", false))
+}
+
+func TestOptionsIncludeImportComments(t *testing.T) {
+	fsys := fstest.MapFS{
+		"vendor.less": {Data: []byte("/*! vendor v1.0 | MIT */\n/* vendor banner */\n.v {\n  /* inside */\n  color: red;\n}\n")},
+	}
+	input := "@import \"vendor.less\";\n/* entry */\n.a { color: blue; }\n"
+	file, err := dst.NewParserWithFS(strings.NewReader(input), fsys).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRenderer().Render(file)
+	require.NoError(t, err)
+	require.Equal(t, "/*! vendor v1.0 | MIT */\n.v {\n  color: red;\n}\n/* entry */\n.a {\n  color: blue;\n}\n", css)
+
+	css, err = NewRendererWithOptions(Options{IncludeImportComments: true}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, "/*! vendor v1.0 | MIT */\n/* vendor banner */\n.v {\n  /* inside */\n  color: red;\n}\n/* entry */\n.a {\n  color: blue;\n}\n", css)
+
+	css, err = NewRendererWithOptions(Options{OutputStyle: OutputCompressed}).Render(file)
+	require.NoError(t, err)
+	require.Equal(t, "/*! vendor v1.0 | MIT */.v{color:red}.a{color:blue}\n", css)
+}
+
+func TestOptionsSourceMapInline(t *testing.T) {
+	input := "@c: red;\n.a {\n  color: @c;\n\n  .b { width: 1px; }\n}\n"
+	file, err := dst.NewParser(strings.NewReader(input)).Parse()
+	require.NoError(t, err)
+
+	css, err := NewRendererWithOptions(Options{SourceMapInline: true, SourceMapFile: "main.less"}).Render(file)
+	require.NoError(t, err)
+
+	css, comment, ok := strings.Cut(css, "/*# sourceMappingURL=data:application/json;base64,")
+	require.True(t, ok)
+	require.Equal(t, ".a {\n  color: red;\n}\n.a .b {\n  width: 1px;\n}\n", css)
+
+	encoded, ok := strings.CutSuffix(comment, " */\n")
+	require.True(t, ok)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	require.NoError(t, err)
+
+	var sourceMap struct {
+		Version  int      `json:"version"`
+		Sources  []string `json:"sources"`
+		Mappings string   `json:"mappings"`
+	}
+	require.NoError(t, json.Unmarshal(data, &sourceMap))
+	require.Equal(t, 3, sourceMap.Version)
+	require.Equal(t, []string{"main.less"}, sourceMap.Sources)
+	// .a is on line 2, color on line 3, .b and width on line 5
+	require.Equal(t, "AACA;EACA;;AAEA;EAAA", sourceMap.Mappings)
+}
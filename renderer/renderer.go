@@ -2,9 +2,13 @@ package renderer
 
 import (
 	"fmt"
+	"maps"
+	"math"
+	"slices"
 	"strconv"
 
 	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
 	"github.com/titpetric/lessgo/dst"
 	"github.com/titpetric/lessgo/evaluator"
 	"github.com/titpetric/lessgo/expression"
@@ -18,7 +22,18 @@ type Renderer struct {
 	mixins       map[string][]*dst.Block
 	mediaQueries []*MediaQuery                 // Collected media queries to render after main content
 	extends      map[string][]string           // Tracks extends: extended selector -> list of extending selectors
+	extendsAll   []extendAll                   // Extends with the all keyword, in source order
 	blockVars    map[string]*dst.BlockVariable // Detached rulesets: @var: { ... }
+	vars         map[string]string             // Global variable overrides
+	deferred     []deferredBlock               // Rules from mixins expanded inside a declaration block
+	media        string                        // Condition of the top-level @media block being rendered
+	bubbled      []bubbledMedia                // Media queries nested in it, rendered after it closes
+	warned       map[*dst.Decl]bool            // Redefinitions already reported
+	guards       map[string]*vm.Program        // Guard expressions compiled in this render, reused by recursive mixins
+	errs         Errors                        // Errors collected with Options.CollectErrors
+	important    bool                          // Expanding a mixin called with !important
+	funcs        *functions.Context            // Settings functions are evaluated with in this render
+	options      Options
 
 	// Pre-allocated buffers for zero-alloc splitting
 	selectorBuf []string // For selector splitting (comma-separated)
@@ -32,6 +47,28 @@ type MediaQuery struct {
 	Depth         int        // The nesting depth
 }
 
+// deferredBlock is a rule produced by a mixin called inside a declaration
+// block. It can't be written in place, so it is rendered after the caller's
+// block closes, with the variables that were visible at the call.
+type deferredBlock struct {
+	block     *dst.Block
+	selectors []string
+	vars      map[string]string
+	important bool                          // the mixin was called with !important
+	blockVars map[string]*dst.BlockVariable // detached rulesets, including ones bound to mixin params
+}
+
+// bubbledMedia is an at-rule nested in a top-level @media or @supports
+// block. A media query in a media query is rendered after the enclosing
+// block with both conditions combined, e.g. "@media screen and (min-width: 768px)".
+type bubbledMedia struct {
+	condition string
+	selName   string // the selector the query was nested in, empty at the top
+	children  []dst.Node
+	vars      map[string]string
+	inside    bool // rendered inside the enclosing at-rule instead of after it
+}
+
 // RenderInterface separates the responsibility to render the syntax tree
 // into two steps. In the `Eval` step, the nested structures are traversed
 // and then produce dst.Nodes with flattened CSS. The Render function is
@@ -73,6 +110,10 @@ type NodeContext struct {
 	// }
 	SelName string
 	BaseDir string // Base directory for resolving relative file paths
+
+	// Selectors holds the full selectors of the block whose declarations
+	// are being rendered, it's empty outside of a declaration block.
+	Selectors []string
 }
 
 func (n *NodeContext) Depth() int {
@@ -81,15 +122,26 @@ func (n *NodeContext) Depth() int {
 
 // NewRenderer creates a new CSS renderer
 func NewRenderer() *Renderer {
+	funcs := &functions.Context{}
 	return &Renderer{
+		resolver:     NewResolverWithContext(nil, funcs),
+		funcs:        funcs,
 		mixins:       make(map[string][]*dst.Block),
 		mediaQueries: make([]*MediaQuery, 0),
 		extends:      make(map[string][]string),
 		blockVars:    make(map[string]*dst.BlockVariable),
+		guards:       make(map[string]*vm.Program),
 		selectorBuf:  make([]string, 0, 16),
 	}
 }
 
+// NewRendererWithOptions creates a new CSS renderer with custom options
+func NewRendererWithOptions(options Options) *Renderer {
+	r := NewRenderer()
+	r.options = options
+	return r
+}
+
 // Render converts a File into CSS output, resolving variables and expressions
 func (r *Renderer) Render(file *dst.File) (string, error) {
 	return r.RenderWithBaseDir(file, "")
@@ -97,10 +149,42 @@ func (r *Renderer) Render(file *dst.File) (string, error) {
 
 // RenderWithBaseDir converts a File into CSS output with a base directory for file resolution
 func (r *Renderer) RenderWithBaseDir(file *dst.File, baseDir string) (string, error) {
-	// Set the base directory for image functions
-	functions.BaseDir = baseDir
+	return r.RenderWithVars(file, baseDir, nil)
+}
+
+// RenderWithVars converts a File into CSS output, overriding global variables.
+// Overrides take precedence over global assignments in the file, so a parsed
+// file can be rendered repeatedly with different themes.
+func (r *Renderer) RenderWithVars(file *dst.File, baseDir string, vars map[string]string) (string, error) {
+	// Functions are evaluated with the options of this render, and the
+	// base directory resolves image paths
+	r.funcs = &functions.Context{
+		Precision:        r.options.Precision,
+		ModernColors:     r.options.ModernColors,
+		FS:               r.options.FS,
+		BaseDir:          baseDir,
+		DataURISizeLimit: r.options.DataURISizeLimit,
+	}
 
-	r.resolver = NewResolver(file)
+	r.resolver = NewResolverWithContext(file, r.funcs)
+
+	// Variable names may be given with or without the @ prefix
+	r.vars = make(map[string]string, len(vars))
+	for name, value := range vars {
+		r.vars[strings.TrimPrefix(name, "@")] = value
+	}
+
+	// Reset collected state so the renderer can be reused
+	r.mixins = make(map[string][]*dst.Block)
+	r.extends = make(map[string][]string)
+	r.extendsAll = nil
+	r.blockVars = make(map[string]*dst.BlockVariable)
+	r.deferred = nil
+	r.media, r.bubbled = "", nil
+	r.important = false
+	r.warned = make(map[*dst.Decl]bool)
+	r.guards = make(map[string]*vm.Program)
+	r.errs = nil
 
 	// First pass: collect mixin definitions, extends, and block variables
 	r.collectMixinsAndExtends(file.Nodes)
@@ -122,11 +206,125 @@ func (r *Renderer) RenderWithBaseDir(file *dst.File, baseDir string) (string, er
 		ctx.Stack.SetGlobal(name, "{}")
 	}
 
-	if err := r.renderNodes(ctx, nil, "", file.Nodes); err != nil {
+	// Seed the global scope with variable overrides
+	for name, value := range r.vars {
+		ctx.Stack.SetGlobal(name, value)
+	}
+
+	if err := r.renderNodes(ctx, nil, "", hoistAtRules(file.Nodes)); err != nil {
 		return "", err
 	}
 
-	return ctx.Buf.String(), nil
+	css := trimWhitespace(formatOutput(ctx.Buf.String(), r.options), r.options.OmitFinalNewline)
+
+	if r.options.SourceMapInline {
+		var err error
+		if css, err = r.appendSourceMap(css); err != nil {
+			return "", err
+		}
+	}
+
+	// Apply the post-processing hook, if configured
+	if r.options.PostProcess != nil {
+		var err error
+		if css, err = r.options.PostProcess(css); err != nil {
+			return "", err
+		}
+	}
+
+	if len(r.errs) > 0 {
+		return css, r.errs
+	}
+	return css, nil
+}
+
+// fail reports err at a source line. It returns err to stop rendering, or
+// with Options.CollectErrors records it and returns nil to keep going.
+func (r *Renderer) fail(line int, err error) error {
+	if line > 0 {
+		err = fmt.Errorf("line %d: %w", line, err)
+	}
+	if !r.options.CollectErrors {
+		return err
+	}
+	r.errs = append(r.errs, err)
+	return nil
+}
+
+// extendAll is an extend with the all keyword, e.g. .b:extend(.a all)
+type extendAll struct {
+	target   string // extended selector, e.g. ".a"
+	extender string // extending selector, e.g. ".b"
+}
+
+// addExtend records that extender extends target. A target ending with the
+// all keyword extends every selector containing it, not just the exact one.
+func (r *Renderer) addExtend(target, extender string) {
+	if rest, ok := strings.CutSuffix(target, " all"); ok {
+		target = strings.TrimSpace(rest)
+		r.extendsAll = append(r.extendsAll, extendAll{target: target, extender: extender})
+	}
+	r.extends[target] = append(r.extends[target], extender)
+}
+
+// extendersOf returns the selectors extending sel, including selectors that
+// extend those extenders. Each selector is visited once, so mutual extends
+// like .a:extend(.b) and .b:extend(.a) terminate.
+func (r *Renderer) extendersOf(sel string) []string {
+	var result []string
+	visited := map[string]bool{sel: true}
+	queue := []string{sel}
+	add := func(extender string) {
+		if visited[extender] {
+			return
+		}
+		visited[extender] = true
+		result = append(result, extender)
+		queue = append(queue, extender)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, extender := range r.extends[current] {
+			add(extender)
+		}
+		for _, ext := range r.extendsAll {
+			if replaced, ok := replaceSelectorPart(current, ext.target, ext.extender); ok {
+				add(replaced)
+			}
+		}
+	}
+	return result
+}
+
+// replaceSelectorPart replaces each occurrence of target in sel with
+// replacement, e.g. ".a:hover" gives ".b:hover" for target ".a". Target only
+// matches whole names, so ".a" doesn't occur in ".ab" or ".x-a".
+func replaceSelectorPart(sel, target, replacement string) (string, bool) {
+	if target == "" {
+		return sel, false
+	}
+	var buf strings.Builder
+	found, i := false, 0
+	for {
+		j := strings.Index(sel[i:], target)
+		if j == -1 {
+			break
+		}
+		start, end := i+j, i+j+len(target)
+		partial := start > 0 && isVarChar(rune(sel[start-1])) && isVarChar(rune(target[0])) ||
+			end < len(sel) && isVarChar(rune(sel[end])) && isVarChar(rune(target[len(target)-1]))
+		if partial {
+			buf.WriteString(sel[i : start+1])
+			i = start + 1
+			continue
+		}
+		buf.WriteString(sel[i:start])
+		buf.WriteString(replacement)
+		i, found = end, true
+	}
+	buf.WriteString(sel[i:])
+	return buf.String(), found
 }
 
 // collectMixinsAndExtends walks the AST to find mixin definitions and extends declarations
@@ -173,6 +371,16 @@ func (r *Renderer) collectMixinsAndExtendsWithPrefix(nodes []dst.Node, prefix st
 				}
 			}
 
+			// Collect extends in selector form, e.g. ".b:extend(.base) { ... }"
+			for _, sel := range block.SelNames {
+				extender, targets := splitExtend(sel)
+				for _, target := range targets {
+					for _, extendedSel := range r.parseExtendSelectors(target) {
+						r.addExtend(extendedSel, extender)
+					}
+				}
+			}
+
 			// Collect extends from this block
 			for _, child := range block.Children {
 				if mixin, ok := child.(*dst.MixinCall); ok {
@@ -187,7 +395,8 @@ func (r *Renderer) collectMixinsAndExtendsWithPrefix(nodes []dst.Node, prefix st
 							// For each selector being extended, track that this block extends it
 							for _, extendedSel := range extendedSelectors {
 								for _, sel := range block.SelNames {
-									r.extends[extendedSel] = append(r.extends[extendedSel], sel)
+									extender, _ := splitExtend(sel)
+									r.addExtend(extendedSel, extender)
 								}
 							}
 						}
@@ -200,7 +409,7 @@ func (r *Renderer) collectMixinsAndExtendsWithPrefix(nodes []dst.Node, prefix st
 			for _, sel := range block.SelNames {
 				newPrefix := prefix
 				if newPrefix != "" {
-					newPrefix = newPrefix + " " + sel
+					newPrefix = newPrefix + " > " + sel
 				} else {
 					newPrefix = sel
 				}
@@ -210,9 +419,56 @@ func (r *Renderer) collectMixinsAndExtendsWithPrefix(nodes []dst.Node, prefix st
 	}
 }
 
+// isVariableAssignment reports whether d assigns a variable (@name: value;),
+// as opposed to a property or a detached ruleset call (@name();)
+func isVariableAssignment(d *dst.Decl) bool {
+	return strings.HasPrefix(d.Key, "@") && !strings.Contains(d.Key, "{") && strings.TrimSpace(d.Value) != "()"
+}
+
+// hoistVariables assigns the variables defined in nodes in order, reporting
+// redefinitions within the scope if configured
+func (r *Renderer) hoistVariables(ctx *NodeContext, nodes []dst.Node) error {
+	var defined map[string]*dst.Decl
+	for _, node := range nodes {
+		decl, ok := node.(*dst.Decl)
+		if !ok || !isVariableAssignment(decl) {
+			continue
+		}
+
+		if r.options.WarnRedefine != nil {
+			if defined == nil {
+				defined = make(map[string]*dst.Decl)
+			}
+			name := strings.TrimPrefix(decl.Key, "@")
+			previous, redefined := defined[name]
+			overrides := previous != nil && previous.Imported && !decl.Imported
+			if redefined && !overrides && !strings.HasSuffix(strings.TrimSpace(decl.Value), "!default") && !r.warned[decl] {
+				r.warned[decl] = true
+				r.options.WarnRedefine(name)
+			}
+			defined[name] = decl
+		}
+
+		if err := r.renderDecl(ctx, decl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // renderNodes renders a slice of nodes
 func (r *Renderer) renderNodes(parentCtx *NodeContext, parent dst.Node, selName string, nodes []dst.Node) error {
+	// Variables are hoisted, so the last definition in a scope wins
+	// even for uses that come before it
+	if err := r.hoistVariables(parentCtx, nodes); err != nil {
+		return err
+	}
+
 	for _, node := range nodes {
+		if decl, ok := node.(*dst.Decl); ok && isVariableAssignment(decl) {
+			continue
+		}
+
 		// For blocks at top level (parent == nil), render them as-is with their selector grouping
 		// For nested content, render for each parent selector if we're in nested context
 		_, isBlock := node.(*dst.Block)
@@ -274,6 +530,8 @@ func (r *Renderer) renderNode(parentCtx *NodeContext, parent dst.Node, selName s
 		return r.renderEach(ctx, n)
 	case *dst.Import:
 		return r.renderImport(ctx, n)
+	case *dst.AtRule:
+		return r.renderAtRule(ctx, n)
 	}
 	return nil
 }
@@ -282,10 +540,48 @@ func (r *Renderer) renderNode(parentCtx *NodeContext, parent dst.Node, selName s
 func (r *Renderer) renderImport(ctx *NodeContext, i *dst.Import) error {
 	ctx.Buf.WriteString("@import \"")
 	ctx.Buf.WriteString(i.Path)
-	ctx.Buf.WriteString("\";\n")
+	ctx.Buf.WriteString("\"")
+	if i.Media != "" {
+		ctx.Buf.WriteString(" ")
+		ctx.Buf.WriteString(i.Media)
+	}
+	ctx.Buf.WriteString(";\n")
+	return nil
+}
+
+// renderAtRule renders a statement at-rule like @charset or @namespace verbatim
+func (r *Renderer) renderAtRule(ctx *NodeContext, a *dst.AtRule) error {
+	ctx.Buf.WriteString("@")
+	ctx.Buf.WriteString(a.Name)
+	ctx.Buf.WriteString(" ")
+	ctx.Buf.WriteString(a.Prelude)
+	ctx.Buf.WriteString(";\n")
 	return nil
 }
 
+// hoistedAtRules must come before any style rules, in this order
+var hoistedAtRules = []string{"charset", "namespace"}
+
+// hoistAtRules moves @charset and then @namespace rules before all other
+// nodes, as CSS requires them ahead of any style rules. Other statement
+// at-rules like @layer keep their place.
+func hoistAtRules(nodes []dst.Node) []dst.Node {
+	result := make([]dst.Node, 0, len(nodes))
+	for _, name := range hoistedAtRules {
+		for _, node := range nodes {
+			if a, ok := node.(*dst.AtRule); ok && a.Name == name {
+				result = append(result, node)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if a, ok := node.(*dst.AtRule); !ok || !slices.Contains(hoistedAtRules, a.Name) {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
 // renderComment renders a comment node
 func (r *Renderer) renderComment(ctx *NodeContext, c *dst.Comment) error {
 	// Skip single-line comments (// style) - lessc omits them from CSS output
@@ -293,8 +589,18 @@ func (r *Renderer) renderComment(ctx *NodeContext, c *dst.Comment) error {
 		return nil
 	}
 
+	// Comments from imports are dropped unless they are /*! important */
+	important := strings.HasPrefix(c.Text, "!")
+	if c.Imported && !important && !r.options.IncludeImportComments {
+		return nil
+	}
+
 	r.writeIndent(ctx.Buf, ctx.Depth()-1)
-	ctx.Buf.WriteString("/* ")
+	if important {
+		ctx.Buf.WriteString("/*")
+	} else {
+		ctx.Buf.WriteString("/* ")
+	}
 	ctx.Buf.WriteString(c.Text)
 	ctx.Buf.WriteString(" */")
 	ctx.Buf.WriteString("\n")
@@ -309,8 +615,8 @@ func (r *Renderer) renderDecl(ctx *NodeContext, d *dst.Decl) error {
 		varName := strings.TrimPrefix(d.Key, "@")
 
 		if blockVar, ok := r.blockVars[varName]; ok {
-			// Render the block variable's children
-			return r.renderNodes(ctx, nil, ctx.SelName, blockVar.Children)
+			// Like a mixin call, nested rules are scoped under the caller
+			return r.expandRuleset(ctx, blockVar.Children)
 		}
 
 		// Block variable not found, skip silently
@@ -324,10 +630,25 @@ func (r *Renderer) renderDecl(ctx *NodeContext, d *dst.Decl) error {
 		varName := strings.TrimPrefix(d.Key, "@")
 		value := d.Value
 
+		// Global assignments don't replace variable overrides
+		if _, ok := r.vars[varName]; ok && ctx.Depth() == 1 {
+			return nil
+		}
+
+		// A !default assignment only applies if the variable isn't defined yet
+		if trimmed := strings.TrimSpace(value); strings.HasSuffix(trimmed, "!default") {
+			if _, ok := ctx.Stack.Get(varName); ok {
+				return nil
+			}
+			value = strings.TrimSpace(strings.TrimSuffix(trimmed, "!default"))
+		}
+
 		// Evaluate the value to resolve any functions or expressions
 		resolved, err := r.resolver.ResolveValue(ctx.Stack, value)
 		if err == nil {
 			value = resolved
+		} else if err := r.fail(d.Line, fmt.Errorf("%s: %w", d.Key, err)); err != nil {
+			return err
 		}
 
 		ctx.Stack.Set(varName, value)
@@ -338,19 +659,38 @@ func (r *Renderer) renderDecl(ctx *NodeContext, d *dst.Decl) error {
 	// Write declaration with proper indentation
 	r.writeIndent(ctx.Buf, ctx.Depth()-1)
 
-	// Apply variable interpolation to property name
+	// Apply variable interpolation to property name. Merged properties
+	// (background+: ...) combine several declarations and aren't mapped.
 	key := r.resolver.InterpolateVariables(ctx.Stack, d.Key)
+	if !strings.HasSuffix(key, "+") && !strings.HasSuffix(key, "+_") {
+		r.writePosition(ctx.Buf, d.Line)
+	}
 	ctx.Buf.WriteString(key)
 	ctx.Buf.WriteString(": ")
 
 	// Skip resolution for CSS3 custom properties (starting with --)
 	value := d.Value
 	if !strings.HasPrefix(d.Key, "--") {
-		resolved, err := r.resolver.ResolveValue(ctx.Stack, value)
-		if err != nil {
-			return err
+		if name, ok := undefinedVariable(ctx.Stack, value); ok {
+			if err := r.fail(d.Line, fmt.Errorf("%s: undefined variable @%s", key, name)); err != nil {
+				return err
+			}
+		} else if resolved, err := r.resolver.ResolveValue(ctx.Stack, value); err != nil {
+			if err := r.fail(d.Line, fmt.Errorf("%s: %w", key, err)); err != nil {
+				return err
+			}
+		} else {
+			value = resolved
 		}
-		value = resolved
+	}
+
+	// Escaped values are written as given, e.g. ~"0px" keeps its unit
+	if r.options.StripZeroUnits && !isEscapedValue(d.Value) {
+		value = stripZeroUnits(key, value)
+	}
+
+	if r.important && !strings.HasSuffix(value, "!important") {
+		value += " !important"
 	}
 
 	ctx.Buf.WriteString(value)
@@ -359,6 +699,46 @@ func (r *Renderer) renderDecl(ctx *NodeContext, d *dst.Decl) error {
 	return nil
 }
 
+// mergeProperties combines rendered declarations using the merge syntax into
+// the first occurrence: "prop+: a" entries are joined with commas and
+// "prop+_: a" entries with spaces, e.g. background+: url(a) -> background: url(a), url(b)
+func mergeProperties(decls string) string {
+	if !strings.Contains(decls, "+: ") && !strings.Contains(decls, "+_: ") {
+		return decls
+	}
+
+	lines := strings.Split(strings.TrimSuffix(decls, "\n"), "\n")
+	merged := make(map[string]int) // property -> index of its first line
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		key, value, ok := strings.Cut(line, ": ")
+		if !ok {
+			result = append(result, line)
+			continue
+		}
+
+		separator := ", "
+		name, found := strings.CutSuffix(key, "+")
+		if !found {
+			name, found = strings.CutSuffix(key, "+_")
+			separator = " "
+		}
+		if !found {
+			result = append(result, line)
+			continue
+		}
+
+		value = strings.TrimSuffix(value, ";")
+		if i, ok := merged[name]; ok {
+			result[i] = strings.TrimSuffix(result[i], ";") + separator + value + ";"
+			continue
+		}
+		merged[name] = len(result)
+		result = append(result, name+": "+value+";")
+	}
+	return strings.Join(result, "\n") + "\n"
+}
+
 // renderBlock renders a block node with nested children
 func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 	// Skip parametric mixin definitions (they're only invoked, not output)
@@ -368,29 +748,36 @@ func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 
 	// If block has guard, evaluate it
 	satisfied, err := r.evaluateGuard(ctx.Stack, b.Guard)
-	if !satisfied || err != nil {
+	if err != nil {
+		return r.fail(b.Line, fmt.Errorf("guard %q: %w", b.Guard.Condition, err))
+	}
+	if !satisfied {
 		return nil
 	}
 
-	// Handle top-level @media blocks specially
-	if len(b.SelNames) > 0 && strings.HasPrefix(b.SelNames[0], "@media") && ctx.SelName == "" {
+	// Handle top-level @media and @supports blocks specially
+	if isConditionalBlock(b) && ctx.SelName == "" {
 		return r.renderTopLevelMediaBlock(ctx, b)
 	}
 
+	// Keyframes aren't combined with a parent selector
+	if isKeyframesBlock(b) {
+		return r.renderKeyframes(ctx, b)
+	}
+
 	// Compute the full selector names for this block (combining parent context)
 	fullSelNames := make([]string, 0, len(b.Names())*2) // preallocate with capacity for names + extends
 	for _, name := range b.Names() {
 		// Apply variable interpolation to selector names
+		name, _ = splitExtend(name)
 		interpolatedName := r.resolver.InterpolateVariables(ctx.Stack, name)
 		fullSelName := selector(ctx.SelName, interpolatedName)
 		fullSelNames = append(fullSelNames, fullSelName)
 
 		// Check if this selector is extended by other selectors
-		if extenders, ok := r.extends[fullSelName]; ok {
-			for _, extender := range extenders {
-				if !contains(fullSelNames, extender) {
-					fullSelNames = append(fullSelNames, extender)
-				}
+		for _, extender := range r.extendersOf(fullSelName) {
+			if !contains(fullSelNames, extender) {
+				fullSelNames = append(fullSelNames, extender)
 			}
 		}
 	}
@@ -399,14 +786,16 @@ func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 	decls := make([]dst.Node, 0, len(b.Children))
 	nestedBlocks := make([]dst.Node, 0, len(b.Children))
 	mediaBlocks := make([]*dst.Block, 0, len(b.Children))
+	var keyframesBlocks []*dst.Block
 	var realDeclCount int // Count of non-variable declarations
 
 	for _, child := range b.Children {
 		if block, isBlock := child.(*dst.Block); isBlock {
-			// Check if this is a media query block
-			isMedia := len(block.SelNames) > 0 && strings.HasPrefix(block.SelNames[0], "@media")
-			if isMedia {
+			// Check if this is a media query or @supports block
+			if isConditionalBlock(block) {
 				mediaBlocks = append(mediaBlocks, block)
+			} else if isKeyframesBlock(block) {
+				keyframesBlocks = append(keyframesBlocks, block)
 			} else {
 				nestedBlocks = append(nestedBlocks, child)
 			}
@@ -430,38 +819,81 @@ func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 		}
 	}
 
+	// Variables of the block, visible to its nested rules and media queries
+	var blockVars map[string]string
+
 	// Only render the block opening/closing if it has real declarations (not just variables)
 	if realDeclCount > 0 {
-		r.writeIndent(ctx.Buf, ctx.Depth()-1)
-		for i, fullSel := range fullSelNames {
-			if i > 0 {
-				ctx.Buf.WriteString(",\n")
-				r.writeIndent(ctx.Buf, ctx.Depth()-1)
-			}
-			ctx.Buf.WriteString(fullSel)
-		}
-
-		ctx.Buf.WriteString(" {\n")
-
 		// Push new scope for block-level variables (used when rendering declarations)
 		ctx.Stack.Push()
 
-		// Render declarations - they will use the increased stack depth for indentation
-		if err := r.renderNodes(ctx, b, ctx.SelName, decls); err != nil {
+		// Render declarations - they will use the increased stack depth for indentation.
+		// They're buffered so merged properties (background+: ...) can be combined
+		// once all declarations and mixins have contributed.
+		declBuf := &strings.Builder{}
+		declCtx := &NodeContext{
+			Buf:       declBuf,
+			Stack:     ctx.Stack,
+			Node:      b,
+			SelName:   ctx.SelName,
+			BaseDir:   ctx.BaseDir,
+			Selectors: fullSelNames,
+		}
+		mark := len(r.deferred)
+		if err := r.renderNodes(declCtx, b, ctx.SelName, decls); err != nil {
 			ctx.Stack.Pop()
 			return err
 		}
 
+		blockVars = ctx.Stack.Local()
 		ctx.Stack.Pop()
 
-		r.writeIndent(ctx.Buf, ctx.Depth()-1)
-		ctx.Buf.WriteString("}\n")
-
-		// Render media queries right after the block's declarations
-		for _, fullSelName := range fullSelNames {
-			if err := r.renderMediaQueriesForSelector(ctx, fullSelName, mediaBlocks); err != nil {
-				return err
+		// A block with only mixin calls bringing nested rules stays empty
+		if declarations := mergeProperties(declBuf.String()); strings.TrimSpace(declarations) != "" {
+			r.writeIndent(ctx.Buf, ctx.Depth()-1)
+			r.writePosition(ctx.Buf, b.Line)
+			for i, fullSel := range fullSelNames {
+				if i > 0 {
+					ctx.Buf.WriteString(",\n")
+					r.writeIndent(ctx.Buf, ctx.Depth()-1)
+				}
+				ctx.Buf.WriteString(fullSel)
 			}
+			ctx.Buf.WriteString(" {\n")
+			ctx.Buf.WriteString(declarations)
+			r.writeIndent(ctx.Buf, ctx.Depth()-1)
+			ctx.Buf.WriteString("}\n")
+		}
+
+		// Rules brought in by mixins follow the block
+		if err := r.renderDeferred(ctx, mark); err != nil {
+			return err
+		}
+	} else if len(decls) > 0 {
+		ctx.Stack.Push()
+		if err := r.hoistVariables(ctx, decls); err != nil {
+			ctx.Stack.Pop()
+			return err
+		}
+		blockVars = ctx.Stack.Local()
+		ctx.Stack.Pop()
+	}
+
+	// Nested rules read the block's variables and may shadow them in their own scope
+	restore := ctx.Stack.Bind(blockVars)
+	defer restore()
+
+	// Render media queries right after the block's declarations
+	for _, fullSelName := range fullSelNames {
+		if err := r.renderMediaQueriesForSelector(ctx, fullSelName, mediaBlocks); err != nil {
+			return err
+		}
+	}
+
+	// Keyframes are rendered once, whatever the number of selectors
+	for _, keyframes := range keyframesBlocks {
+		if err := r.renderKeyframes(ctx, keyframes); err != nil {
+			return err
 		}
 	}
 
@@ -486,6 +918,9 @@ func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 		// Render grouped nested selectors if we have any
 		if len(allNestedSelectors) > 0 {
 			r.writeIndent(ctx.Buf, ctx.Depth()-1)
+			if first, ok := nestedBlocks[0].(*dst.Block); ok {
+				r.writePosition(ctx.Buf, first.Line)
+			}
 			for i, sel := range allNestedSelectors {
 				if i > 0 {
 					ctx.Buf.WriteString(",\n")
@@ -538,6 +973,18 @@ func (r *Renderer) renderBlock(ctx *NodeContext, b *dst.Block) error {
 	return nil
 }
 
+// evaluateGuardFunctions replaces registered function calls in a guard
+// condition with their results, e.g. "length(@list) > 2" becomes "3 > 2"
+func (r *Renderer) evaluateGuardFunctions(stack *Stack, condition string) string {
+	initEvaluableFuncNames()
+	for _, call := range extractFunctionCalls(condition, registeredFuncSearches) {
+		if resolved, err := r.resolver.ResolveValue(stack, call); err == nil {
+			condition = strings.ReplaceAll(condition, call, resolved)
+		}
+	}
+	return condition
+}
+
 // evaluateGuard
 func (r *Renderer) evaluateGuard(stack *Stack, g *dst.Guard) (bool, error) {
 	if !g.Valid() {
@@ -567,64 +1014,94 @@ func (r *Renderer) evaluateGuard(stack *Stack, g *dst.Guard) (bool, error) {
 		}
 	}
 
+	// default() is true only while rendering a default mixin variant
+	condition = strings.ReplaceAll(condition, "default()", r.funcs.Default())
+
+	// !important is a flag of a declaration, not a value to compare
+	condition = strings.ReplaceAll(condition, "!important", "")
+
+	// Function calls like length(@list) are evaluated with the bound arguments
+	condition = r.evaluateGuardFunctions(stack, condition)
+
 	// Parse the LESS guard condition tokens to prepare for evaluation
 	tokens, err := evaluator.Tokenize(condition)
 	if err != nil {
 		return false, err
 	}
 
-	// Build a Go expression from the tokens
+	vars := stack.All()
+
+	// Build a Go expression from the tokens, with the unit kind of each part
 	exprParts := make([]string, 0, len(tokens))
+	kinds := make([]string, 0, len(tokens))
 	for _, t := range tokens {
+		kind := ""
 		switch t.Type {
 		case evaluator.TokenIdent:
 			// Variable reference - drop the @ and use the variable name
-			exprParts = append(exprParts, strings.TrimPrefix(t.Text, "@"))
+			name := strings.TrimPrefix(t.Text, "@")
+			value, _ := cutImportant(vars[name])
+			_, kind, _ = parseNumberForGuard(value)
+			exprParts = append(exprParts, name)
 		case evaluator.TokenOp:
-			// Map = to ==
-			if t.Text == "=" {
+			// Map LESS comparison operators to expr syntax
+			switch t.Text {
+			case "=":
 				exprParts = append(exprParts, "==")
-			} else {
+			case "=<":
+				exprParts = append(exprParts, "<=")
+			case "=>":
+				exprParts = append(exprParts, ">=")
+			default:
 				exprParts = append(exprParts, t.Text)
 			}
 		case evaluator.TokenValue:
 			// Try to parse as a number (with or without units), otherwise quote as string
-			numVal := parseNumberForGuard(t.Text)
-			if numVal != nil {
-				exprParts = append(exprParts, fmt.Sprint(numVal))
+			if num, numKind, ok := parseNumberForGuard(t.Text); ok {
+				exprParts = append(exprParts, fmt.Sprint(num))
+				kind = numKind
+			} else if t.Text == "true" || t.Text == "false" {
+				exprParts = append(exprParts, t.Text)
 			} else {
 				// It's a non-numeric value, quote it
-				exprParts = append(exprParts, fmt.Sprintf("%q", t.Text))
+				exprParts = append(exprParts, fmt.Sprintf("%q", guardString(t.Text)))
 			}
 		case evaluator.TokenParen:
 			exprParts = append(exprParts, t.Text)
+		default:
+			continue
 		}
+		kinds = append(kinds, kind)
 	}
+	compareKinds(exprParts, kinds)
 
 	goExpr := strings.Join(exprParts, " ")
 
-	vars := stack.All()
-
 	// Don't use Eval/EvalBool - they call preprocessExpression which re-parses the expression
 	// Instead compile and run directly with expr library, using pre-processed variables
 	evalVars := make(map[string]interface{})
 	for k, v := range vars {
-		// Convert string variables to appropriate types for expr evaluation
-		numVal := parseNumberForGuard(v)
-		if numVal != nil {
-			evalVars[k] = numVal
+		// Convert string variables to appropriate types for expr evaluation,
+		// 5px !important compares as 5px
+		v, _ = cutImportant(v)
+		if num, _, ok := parseNumberForGuard(v); ok {
+			evalVars[k] = num
 		} else if v == "true" {
 			evalVars[k] = true
 		} else if v == "false" {
 			evalVars[k] = false
 		} else {
-			evalVars[k] = v
+			evalVars[k] = guardString(v)
 		}
 	}
 
-	program, err := expr.Compile(goExpr, expr.AllowUndefinedVariables())
-	if err != nil {
-		return false, err
+	program, ok := r.guards[goExpr]
+	if !ok {
+		program, err = expr.Compile(goExpr, expr.AllowUndefinedVariables())
+		if err != nil {
+			return false, err
+		}
+		r.guards[goExpr] = program
 	}
 
 	result, err := expr.Run(program, evalVars)
@@ -650,76 +1127,256 @@ func (r *Renderer) evaluateGuard(stack *Stack, g *dst.Guard) (bool, error) {
 
 // renderMixinCall renders a mixin call by expanding it
 func (r *Renderer) renderMixinCall(ctx *NodeContext, m *dst.MixinCall) error {
-	// Find the mixin definition
+	// Find the mixin definition, namespaces may be separated by > or spaces
 	blocks, ok := r.mixins[m.Name]
 	if !ok {
-		return nil
+		blocks, ok = r.mixins[mixinPath(m.Name)]
+	}
+	if !ok {
+		return r.fail(m.Line, fmt.Errorf("undefined mixin %s", m.Name))
+	}
+
+	// Resolve arguments once, they are used for literal pattern matching and binding.
+	// Detached rulesets, inline or by @name, are bound separately.
+	args := make([]string, len(m.Args))
+	rulesets := make(map[int]*dst.BlockVariable, len(m.Rulesets))
+	for i, arg := range m.Args {
+		args[i] = arg
+		if ruleset, ok := m.Rulesets[i]; ok {
+			rulesets[i] = ruleset
+			continue
+		}
+		if ruleset, ok := r.blockVars[strings.TrimPrefix(arg, "@")]; ok && strings.HasPrefix(arg, "@") {
+			rulesets[i] = ruleset
+			continue
+		}
+		// Try to resolve the argument value (in case it contains expressions like @var or operations)
+		if resolved, err := r.resolver.ResolveValue(ctx.Stack, arg); err == nil {
+			args[i] = resolved
+		}
 	}
 
-	// First pass: find exact arity match (pattern matching by argument count)
-	// LESS supports mixin overloading by arity
-	var bestMatch *dst.Block
+	// First pass: find exact arity matches (pattern matching by argument count)
+	// LESS supports mixin overloading by arity and by literal parameter values
+	candidates := make([]*dst.Block, 0, len(blocks))
 	for _, b := range blocks {
 		// Check if this mixin matches the argument count
-		if len(b.Params) == len(m.Args) {
-			bestMatch = b
-			break // Use first exact match
+		if len(b.Params) == len(args) && matchesPatterns(b.Params, args) {
+			candidates = append(candidates, b)
 		}
 	}
 
-	// If no exact match found, look for best fallback (mixin with fewer params can accept extra args)
-	if bestMatch == nil {
+	// If no exact match found, look for fallbacks (mixin with fewer params can accept extra args)
+	if len(candidates) == 0 {
 		for _, b := range blocks {
-			// Use first mixin that has params (or no params if call has no args)
-			if len(b.Params) == 0 && len(m.Args) == 0 {
-				bestMatch = b
-				break
-			} else if len(b.Params) > 0 && len(m.Args) > 0 {
-				bestMatch = b
-				break
-			}
-		}
-	}
-
-	// If we found a matching mixin, render it
-	if bestMatch != nil {
-		// Set parameters as variables in the current scope (Stack push/pop is handled by renderBlock)
-		if len(bestMatch.Params) > 0 && len(m.Args) > 0 {
-			for i, param := range bestMatch.Params {
-				if i < len(m.Args) {
-					// Remove @ from parameter name
-					paramName := strings.TrimPrefix(param, "@")
-					argValue := m.Args[i]
-
-					// Try to resolve the argument value (in case it contains expressions like @var or operations)
-					resolved, err := r.resolver.ResolveValue(ctx.Stack, argValue)
-					if err == nil {
-						argValue = resolved
-					}
+			// Use mixins that have params (or no params if call has no args)
+			if len(b.Params) == 0 && len(args) == 0 {
+				candidates = append(candidates, b)
+			} else if len(b.Params) > 0 && len(args) > 0 && matchesPatterns(b.Params, args) {
+				candidates = append(candidates, b)
+			}
+		}
+	}
 
-					ctx.Stack.Set(paramName, argValue)
-				}
+	// Declarations of a mixin called with !important are all marked important,
+	// including those of nested rules and mixins it calls
+	if m.Important && !r.important {
+		r.important = true
+		defer func() { r.important = false }()
+	}
+
+	// Render every candidate whose guard is satisfied by the bound arguments,
+	// in source order. Variants using default() are evaluated afterwards,
+	// default() is true when no other variant matched.
+	var defaults []*dst.Block
+	matched := false
+	for _, candidate := range candidates {
+		if candidate.Guard.Valid() && strings.Contains(candidate.Guard.Condition, "default()") {
+			defaults = append(defaults, candidate)
+			continue
+		}
+		rendered, err := r.renderMixinCandidate(ctx, candidate, args, rulesets)
+		if err != nil {
+			return err
+		}
+		matched = matched || rendered
+	}
+
+	if len(defaults) == 0 {
+		return nil
+	}
+
+	previous := r.funcs.DefaultMatch
+	r.funcs.DefaultMatch = !matched
+	defer func() { r.funcs.DefaultMatch = previous }()
+
+	for _, candidate := range defaults {
+		if _, err := r.renderMixinCandidate(ctx, candidate, args, rulesets); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// mixinPath normalizes a namespaced mixin name like "#ns #inner.m",
+// "#ns.m" or "#ns>#inner > .m" to the "#ns > #inner > .m" form mixins are
+// registered by. Names are split at spaces, > and each unescaped . or #.
+func mixinPath(name string) string {
+	var parts []string
+	start := -1
+	for i := 0; i < len(name); i++ {
+		switch ch := name[i]; {
+		case ch == ' ' || ch == '\t' || ch == '>':
+			if start != -1 {
+				parts = append(parts, name[start:i])
+				start = -1
 			}
+		case (ch == '.' || ch == '#') && start != -1 && name[i-1] != '\\':
+			parts = append(parts, name[start:i])
+			start = i
+		case start == -1:
+			start = i
 		}
+	}
+	if start != -1 {
+		parts = append(parts, name[start:])
+	}
+	return strings.Join(parts, " > ")
+}
 
-		// If block has guard, evaluate it
-		satisfied, err := r.evaluateGuard(ctx.Stack, bestMatch.Guard)
-		if !satisfied || err != nil {
-			return nil
+// renderMixinCandidate binds the arguments and renders the mixin if its guard is
+// satisfied. Parameters are unbound afterwards, so they don't shadow variables
+// of the caller or its sibling rules. Parameters receiving a detached ruleset
+// are called like one, e.g. @content();
+func (r *Renderer) renderMixinCandidate(ctx *NodeContext, candidate *dst.Block, args []string, rulesets map[int]*dst.BlockVariable) (bool, error) {
+	params := make(map[string]string, len(candidate.Params))
+	var blockVars map[string]*dst.BlockVariable
+	for i, param := range candidate.Params {
+		// Literal pattern params don't bind a variable
+		if i >= len(args) || !strings.HasPrefix(param, "@") {
+			continue
 		}
+		// Remove @ from parameter name
+		name := strings.TrimPrefix(param, "@")
+		if ruleset, ok := rulesets[i]; ok {
+			if blockVars == nil {
+				blockVars = maps.Clone(r.blockVars)
+			}
+			blockVars[name] = ruleset
+			continue
+		}
+		params[name] = args[i]
+	}
+	restore := ctx.Stack.Bind(params)
+	defer restore()
+
+	if blockVars != nil {
+		outerBlockVars := r.blockVars
+		r.blockVars = blockVars
+		defer func() { r.blockVars = outerBlockVars }()
+	}
+
+	// If block has guard, evaluate it
+	satisfied, err := r.evaluateGuard(ctx.Stack, candidate.Guard)
+	if err != nil {
+		return false, r.fail(candidate.Line, fmt.Errorf("guard %q: %w", candidate.Guard.Condition, err))
+	}
+	if !satisfied {
+		return false, nil
+	}
 
-		// Render mixin children
-		// Pass parent=nil so blocks within the mixin are rendered at the correct nesting level
-		// If we're rendering a top-level mixin call, children should be top-level
-		// If we're in a nested context, children should be nested under the current selName
-		if err := r.renderNodes(ctx, nil, ctx.SelName, bestMatch.Children); err != nil {
+	return true, r.expandRuleset(ctx, candidate.Children)
+}
+
+// expandRuleset renders the children of a mixin or detached ruleset into the
+// caller. Declarations are written in place; nested rules are deferred until
+// the caller's block is closed and then scoped under the caller's selectors.
+func (r *Renderer) expandRuleset(ctx *NodeContext, children []dst.Node) error {
+	// Pass parent=nil so blocks within the mixin are rendered at the correct nesting level
+	// If we're rendering a top-level mixin call, children should be top-level
+	if len(ctx.Selectors) == 0 {
+		return r.renderNodes(ctx, nil, ctx.SelName, children)
+	}
+
+	if err := r.hoistVariables(ctx, children); err != nil {
+		return err
+	}
+
+	for _, child := range children {
+		if block, ok := child.(*dst.Block); ok && !block.IsMixinFunction {
+			r.deferred = append(r.deferred, deferredBlock{
+				block:     block,
+				selectors: ctx.Selectors,
+				vars:      ctx.Stack.All(),
+				important: r.important,
+				blockVars: r.blockVars,
+			})
+			continue
+		}
+		if decl, ok := child.(*dst.Decl); ok && isVariableAssignment(decl) {
+			continue
+		}
+		if err := r.renderNode(ctx, nil, "", child); err != nil {
 			return err
 		}
 	}
+	return nil
+}
 
+// renderDeferred renders the rules deferred since mark at the current depth
+func (r *Renderer) renderDeferred(ctx *NodeContext, mark int) error {
+	if len(r.deferred) <= mark {
+		return nil
+	}
+
+	pending := append([]deferredBlock(nil), r.deferred[mark:]...)
+	r.deferred = r.deferred[:mark]
+
+	outerImportant, outerBlockVars := r.important, r.blockVars
+	defer func() { r.important, r.blockVars = outerImportant, outerBlockVars }()
+
+	for _, d := range pending {
+		r.important, r.blockVars = d.important, d.blockVars
+		restore := ctx.Stack.Bind(d.vars)
+		for _, sel := range d.selectors {
+			blockCtx := &NodeContext{
+				Buf:     ctx.Buf,
+				Stack:   ctx.Stack,
+				Node:    d.block,
+				SelName: sel,
+				BaseDir: ctx.BaseDir,
+			}
+
+			var err error
+			if isConditionalBlock(d.block) {
+				err = r.renderMediaQueriesForSelector(blockCtx, sel, []*dst.Block{d.block})
+			} else {
+				err = r.renderBlock(blockCtx, d.block)
+			}
+			if err != nil {
+				restore()
+				return err
+			}
+		}
+		restore()
+	}
 	return nil
 }
 
+// matchesPatterns reports whether literal (non-@) mixin params equal the corresponding arguments
+func matchesPatterns(params []string, args []string) bool {
+	for i, param := range params {
+		if strings.HasPrefix(param, "@") {
+			continue
+		}
+		if i >= len(args) || param != args[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // parseExtendSelectors parses a selector string that may contain multiple selectors
 // e.g., ".base, .success" or ".base .success" (zero-alloc)
 func (r *Renderer) parseExtendSelectors(selString string) []string {
@@ -730,7 +1387,9 @@ func (r *Renderer) parseExtendSelectors(selString string) []string {
 		strings.SplitCommaNoAlloc(selString, &r.selectorBuf)
 		// Make a copy since the buffer will be reused
 		result := make([]string, len(r.selectorBuf))
-		copy(result, r.selectorBuf)
+		for i, sel := range r.selectorBuf {
+			result[i] = strings.TrimSpace(sel)
+		}
 		return result
 	}
 
@@ -738,6 +1397,38 @@ func (r *Renderer) parseExtendSelectors(selString string) []string {
 	return []string{selString}
 }
 
+// splitExtend splits a selector with an ":extend(...)" suffix into the plain
+// selector and the extend targets, e.g. ".b:extend(.a)" gives ".b" and [".a"].
+func splitExtend(sel string) (string, []string) {
+	idx := strings.Index(sel, ":extend(")
+	if idx == -1 || !strings.HasSuffix(sel, ")") {
+		return sel, nil
+	}
+
+	var targets []string
+	rest := sel[idx:]
+	for strings.HasPrefix(rest, ":extend(") {
+		// The closing parenthesis at depth 0, targets may hold :not(...)
+		end, depth := -1, 0
+		for i := len(":extend"); i < len(rest) && end == -1; i++ {
+			switch rest[i] {
+			case '(':
+				depth++
+			case ')':
+				if depth--; depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end == -1 {
+			return sel, nil
+		}
+		targets = append(targets, rest[len(":extend("):end])
+		rest = rest[end+1:]
+	}
+	return strings.TrimSpace(sel[:idx]), targets
+}
+
 // contains checks if a string slice contains a value
 func contains(slice []string, value string) bool {
 	for _, s := range slice {
@@ -756,108 +1447,377 @@ func (r *Renderer) writeIndent(buf *strings.Builder, depth int) {
 }
 
 // renderMediaQueriesForSelector renders media query blocks for a specific parent selector
+// Media blocks whose conditions resolve to the same query are merged.
 func (r *Renderer) renderMediaQueriesForSelector(ctx *NodeContext, parentSelName string, mediaBlocks []*dst.Block) error {
+	// Group children by resolved condition, keeping first-seen order
+	conditions := make([]string, 0, len(mediaBlocks))
+	children := make(map[string][]dst.Node, len(mediaBlocks))
 	for _, mediaBlock := range mediaBlocks {
 		if len(mediaBlock.SelNames) == 0 {
 			continue
 		}
 
-		condition := mediaBlock.SelNames[0] // "@media ..."
+		condition, err := r.resolvePrelude(ctx.Stack, mediaBlock) // "@media ..."
+		if err != nil {
+			return err
+		}
+		if _, ok := children[condition]; !ok {
+			conditions = append(conditions, condition)
+		}
+		children[condition] = append(children[condition], mediaBlock.Children...)
+	}
 
-		// Write the media query
-		r.writeIndent(ctx.Buf, ctx.Depth()-1)
-		ctx.Buf.WriteString(condition)
-		ctx.Buf.WriteString(" {\n")
+	vars := ctx.Stack.All()
+	for _, condition := range conditions {
+		// Inside a top-level at-rule the queries are nested in or follow it
+		if r.media != "" {
+			r.bubbleMedia(condition, parentSelName, children[condition], vars)
+			continue
+		}
+		if err := r.renderMedia(ctx, condition, parentSelName, children[condition]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		// Push scope for media query content
-		ctx.Stack.Push()
+// renderTopLevelMediaBlock renders a top-level @media or @supports block (not nested inside another selector)
+func (r *Renderer) renderTopLevelMediaBlock(ctx *NodeContext, b *dst.Block) error {
+	condition, err := r.resolvePrelude(ctx.Stack, b) // "@media ..."
+	if err != nil {
+		return err
+	}
+	return r.renderMedia(ctx, condition, "", b.Children)
+}
+
+// resolvePrelude resolves the prelude of an at-rule block like
+// "@media @tablet", reporting variables that aren't defined
+func (r *Renderer) resolvePrelude(stack *Stack, b *dst.Block) (string, error) {
+	keyword, condition, _ := strings.Cut(b.SelNames[0], " ")
+	if name, ok := undefinedVariable(stack, condition); ok {
+		if err := r.fail(b.Line, fmt.Errorf("%s: undefined variable @%s", keyword, name)); err != nil {
+			return "", err
+		}
+	}
+	return r.resolver.ResolveMediaQuery(stack, b.SelNames[0]), nil
+}
+
+// renderKeyframes renders a @keyframes block. The frames are rendered as
+// rules without a parent selector, so mixin calls in them expand in place.
+// Keyframes nested in a rule follow it, an empty block is omitted.
+func (r *Renderer) renderKeyframes(ctx *NodeContext, b *dst.Block) error {
+	body := &strings.Builder{}
+	bodyCtx := &NodeContext{
+		Buf:     body,
+		Stack:   ctx.Stack,
+		BaseDir: ctx.BaseDir,
+	}
+
+	ctx.Stack.Push()
+	err := r.renderNodes(bodyCtx, nil, "", b.Children)
+	ctx.Stack.Pop()
+	if err != nil {
+		return err
+	}
 
-		// Write the parent selector within the media query
+	if strings.TrimSpace(body.String()) != "" {
+		prelude, err := r.resolvePrelude(ctx.Stack, b)
+		if err != nil {
+			return err
+		}
 		r.writeIndent(ctx.Buf, ctx.Depth()-1)
-		ctx.Buf.WriteString(parentSelName)
+		ctx.Buf.WriteString(prelude)
 		ctx.Buf.WriteString(" {\n")
+		ctx.Buf.WriteString(body.String())
+		r.writeIndent(ctx.Buf, ctx.Depth()-1)
+		ctx.Buf.WriteString("}\n")
+	}
+	return nil
+}
 
-		// Render the children
-		mediaCtx := &NodeContext{
-			Buf:     ctx.Buf,
-			Stack:   ctx.Stack,
-			Node:    nil,
-			SelName: parentSelName,
-			BaseDir: ctx.BaseDir,
-		}
+// renderMedia renders a conditional at-rule holding children, wrapped in
+// selName when it isn't empty. Media queries nested anywhere within a media
+// query are combined with its condition and rendered after it, other nested
+// at-rules are rendered inside it. An at-rule without rules is omitted.
+func (r *Renderer) renderMedia(ctx *NodeContext, condition, selName string, children []dst.Node) error {
+	outerMedia, outerBubbled := r.media, r.bubbled
+	r.media, r.bubbled = condition, nil
+	defer func() {
+		r.media, r.bubbled = outerMedia, outerBubbled
+	}()
+
+	body := &strings.Builder{}
+	bodyCtx := &NodeContext{
+		Buf:     body,
+		Stack:   ctx.Stack,
+		SelName: selName,
+		BaseDir: ctx.BaseDir,
+	}
 
-		// Push another scope for proper indentation
-		ctx.Stack.Push()
+	// Content nested in a selector is rendered as a block of that selector,
+	// so deeper rules are flattened and deeper queries combine with this one
+	if selName != "" {
+		children = []dst.Node{&dst.Block{SelNames: []string{"&"}, Children: children}}
+	}
 
-		for _, child := range mediaBlock.Children {
-			if err := r.renderNode(mediaCtx, nil, "", child); err != nil {
-				ctx.Stack.Pop()
+	// Push scope for media query content
+	ctx.Stack.Push()
+
+	mark := len(r.deferred)
+	for _, child := range children {
+		if block, ok := child.(*dst.Block); ok && isConditionalBlock(block) {
+			nested, err := r.resolvePrelude(ctx.Stack, block)
+			if err != nil {
 				ctx.Stack.Pop()
 				return err
 			}
+			r.bubbleMedia(nested, selName, block.Children, ctx.Stack.All())
+			continue
 		}
+		if err := r.renderNode(bodyCtx, nil, "", child); err != nil {
+			ctx.Stack.Pop()
+			return err
+		}
+	}
 
-		ctx.Stack.Pop()
+	err := r.renderDeferred(bodyCtx, mark)
+	for _, nested := range r.bubbled {
+		if err != nil {
+			break
+		}
+		if nested.inside {
+			restore := ctx.Stack.Bind(nested.vars)
+			err = r.renderMedia(bodyCtx, nested.condition, nested.selName, nested.children)
+			restore()
+		}
+	}
+	ctx.Stack.Pop()
+	if err != nil {
+		return err
+	}
 
+	if strings.TrimSpace(body.String()) != "" {
 		r.writeIndent(ctx.Buf, ctx.Depth()-1)
-		ctx.Buf.WriteString("}\n")
-
-		ctx.Stack.Pop()
-
+		ctx.Buf.WriteString(condition)
+		ctx.Buf.WriteString(" {\n")
+		ctx.Buf.WriteString(body.String())
 		r.writeIndent(ctx.Buf, ctx.Depth()-1)
 		ctx.Buf.WriteString("}\n")
 	}
 
+	for _, nested := range r.bubbled {
+		if nested.inside {
+			continue
+		}
+		restore := ctx.Stack.Bind(nested.vars)
+		err := r.renderMedia(ctx, nested.condition, nested.selName, nested.children)
+		restore()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-// renderTopLevelMediaBlock renders a top-level @media block (not nested inside another selector)
-func (r *Renderer) renderTopLevelMediaBlock(ctx *NodeContext, b *dst.Block) error {
-	condition := b.SelNames[0] // "@media ..."
+// bubbleMedia queues an at-rule nested in the top-level at-rule being
+// rendered. A media query in a media query is combined with the enclosing
+// condition, others stay nested, e.g. @supports inside @media.
+func (r *Renderer) bubbleMedia(condition, selName string, children []dst.Node, vars map[string]string) {
+	inside := !isMediaQuery(r.media) || !isMediaQuery(condition)
+	if !inside {
+		condition = combineMedia(r.media, condition)
+	}
+	r.bubbled = append(r.bubbled, bubbledMedia{
+		condition: condition,
+		selName:   selName,
+		children:  children,
+		vars:      vars,
+		inside:    inside,
+	})
+}
 
-	// Write the media query opening
-	ctx.Buf.WriteString(condition)
-	ctx.Buf.WriteString(" {\n")
+// conditionalAtRules hold rules and nest and bubble like @media
+var conditionalAtRules = []string{"@media", "@supports", "@container", "@layer", "@scope"}
 
-	// Push scope for media query content
-	ctx.Stack.Push()
+// isConditionalBlock reports whether b is an at-rule block like @media or
+// @container, which holds rules and is rendered around the selector it's
+// nested in
+func isConditionalBlock(b *dst.Block) bool {
+	if len(b.SelNames) == 0 {
+		return false
+	}
+	for _, keyword := range conditionalAtRules {
+		if hasAtKeyword(b.SelNames[0], keyword) {
+			return true
+		}
+	}
+	return false
+}
 
-	// Render the children (which are blocks like .container, h1, etc.)
-	for _, child := range b.Children {
-		if err := r.renderNode(ctx, nil, "", child); err != nil {
-			ctx.Stack.Pop()
-			return err
+// isKeyframesBlock reports whether b is an @keyframes block, including
+// vendor prefixed forms like @-webkit-keyframes
+func isKeyframesBlock(b *dst.Block) bool {
+	if len(b.SelNames) == 0 || !strings.HasPrefix(b.SelNames[0], "@") {
+		return false
+	}
+	keyword, _, _ := strings.Cut(b.SelNames[0], " ")
+	return strings.HasSuffix(keyword, "keyframes")
+}
+
+// isMediaQuery reports whether prelude is an @media query
+func isMediaQuery(prelude string) bool {
+	return hasAtKeyword(prelude, "@media")
+}
+
+// hasAtKeyword reports whether prelude starts with the at-rule keyword
+func hasAtKeyword(prelude, keyword string) bool {
+	if !strings.HasPrefix(prelude, keyword) {
+		return false
+	}
+	rest := prelude[len(keyword):]
+	return rest == "" || rest[0] == ' ' || rest[0] == '(' || rest[0] == '\t'
+}
+
+// combineMedia joins an outer and a nested media query with "and". Each
+// query in a comma separated list is combined with each of the other:
+// "@media screen, print" and "@media (color)" give
+// "@media screen and (color), print and (color)".
+func combineMedia(outer, inner string) string {
+	outer = strings.TrimSpace(strings.TrimPrefix(outer, "@media"))
+	inner = strings.TrimSpace(strings.TrimPrefix(inner, "@media"))
+	if outer == "" {
+		return "@media " + inner
+	}
+	if inner == "" {
+		return "@media " + outer
+	}
+
+	var combined []string
+	for _, o := range strings.Split(outer, ",") {
+		for _, i := range strings.Split(inner, ",") {
+			combined = append(combined, strings.TrimSpace(o)+" and "+strings.TrimSpace(i))
 		}
 	}
+	return "@media " + strings.Join(combined, ", ")
+}
 
-	ctx.Stack.Pop()
+// guardString normalizes a non-numeric guard operand for comparison.
+// Quoted strings compare by their content, case-sensitively, so "dark mode"
+// equals a "dark mode" argument. Keywords compare case-insensitively, so
+// Bold equals bold. A quoted string equals a keyword with the same text.
+// Colors compare by their resolved value, so #fff equals white.
+func guardString(value string) string {
+	if unquoted := unquote(value); unquoted != value {
+		return guardColor(unquoted)
+	}
+	if isGuardKeyword(value) {
+		value = strings.ToLower(value)
+	}
+	return guardColor(value)
+}
 
-	ctx.Buf.WriteString("}\n")
+// guardColor returns colors in hex notation and other values unchanged
+func guardColor(value string) string {
+	if !functions.IsColor(value) {
+		return value
+	}
+	if c, err := functions.ParseColor(strings.ToLower(value)); err == nil {
+		return c.ToHex()
+	}
+	return value
+}
 
-	return nil
+// isGuardKeyword reports whether value is a bare identifier like bold or sans-serif
+func isGuardKeyword(value string) bool {
+	if value == "" {
+		return false
+	}
+	for i := 0; i < len(value); i++ {
+		if !isVarChar(rune(value[i])) {
+			return false
+		}
+	}
+	return true
+}
+
+// guardUnit converts a unit stripped from numbers compared in guards to the
+// common unit of its kind
+type guardUnit struct {
+	kind   string
+	factor float64
 }
 
-// parseNumberForGuard tries to parse a value as a number, stripping CSS units
-func parseNumberForGuard(value string) interface{} {
+// guardUnits are the units of numbers compared in guards, so 1s > 500ms and
+// 1in = 96px hold. Relative units like em and % can't be converted and are a
+// kind of their own, like the functions 50% compares as 50.
+var guardUnits = map[string]guardUnit{
+	// Absolute lengths, in px
+	"px": {"length", 1}, "in": {"length", 96}, "cm": {"length", 96 / 2.54}, "mm": {"length", 96 / 25.4},
+	"q": {"length", 96 / 101.6}, "pt": {"length", 96.0 / 72}, "pc": {"length", 16},
+	// Times, in s
+	"s": {"time", 1}, "ms": {"time", 0.001},
+	// Angles, in deg
+	"deg": {"angle", 1}, "rad": {"angle", 180 / math.Pi}, "grad": {"angle", 0.9}, "turn": {"angle", 360},
+	// Resolutions, in dppx
+	"dppx": {"resolution", 1}, "dpi": {"resolution", 1.0 / 96}, "dpcm": {"resolution", 2.54 / 96},
+	// Relative units
+	"em": {"em", 1}, "rem": {"rem", 1}, "%": {"%", 1}, "ex": {"ex", 1}, "ch": {"ch", 1},
+	"vw": {"vw", 1}, "vh": {"vh", 1}, "vmin": {"vmin", 1}, "vmax": {"vmax", 1}, "fr": {"fr", 1},
+}
+
+// parseNumberForGuard tries to parse a value as a number, converting CSS
+// units of the same kind to a common unit. The kind of the unit is returned
+// with it, unitless numbers have an empty kind.
+func parseNumberForGuard(value string) (float64, string, bool) {
 	value = strings.TrimSpace(value)
 
 	// Try direct float parse
 	if num, err := strconv.ParseFloat(value, 64); err == nil {
-		return num
+		return num, "", true
 	}
 
-	// Try stripping common CSS units
-	units := []string{"px", "em", "rem", "pt", "cm", "mm", "in", "pc", "ex", "ch", "vw", "vh", "vmin", "vmax"}
-	for _, unit := range units {
-		if strings.HasSuffix(value, unit) {
-			numStr := strings.TrimSuffix(value, unit)
-			if num, err := strconv.ParseFloat(numStr, 64); err == nil {
-				return num
-			}
-		}
+	// Split the number from its unit
+	end := 0
+	for end < len(value) && (value[end] >= '0' && value[end] <= '9' || value[end] == '.' || end == 0 && (value[end] == '-' || value[end] == '+')) {
+		end++
 	}
+	unit, ok := guardUnits[strings.ToLower(value[end:])]
+	if !ok {
+		return 0, "", false
+	}
+	num, err := strconv.ParseFloat(value[:end], 64)
+	if err != nil {
+		return 0, "", false
+	}
+	// Round off conversion noise, so 2.54cm = 96px
+	return math.Round(num*unit.factor*1e9) / 1e9, unit.kind, true
+}
 
-	return nil
+// guardComparisons are the comparison operators of a compiled guard
+var guardComparisons = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+// compareKinds replaces comparisons of numbers of different kinds, like
+// 1s = 1px, with false. kinds holds the unit kind of each part, "" if it
+// isn't a number with a unit. Operands of arithmetic are left alone.
+func compareKinds(parts, kinds []string) {
+	arithmetic := func(i int) bool {
+		if i < 0 || i >= len(parts) {
+			return false
+		}
+		switch parts[i] {
+		case "+", "-", "*", "/", "%":
+			return true
+		}
+		return false
+	}
+	for i := 1; i+1 < len(parts); i++ {
+		if !guardComparisons[parts[i]] || arithmetic(i-2) || arithmetic(i+2) {
+			continue
+		}
+		if kinds[i-1] != "" && kinds[i+1] != "" && kinds[i-1] != kinds[i+1] {
+			parts[i-1], parts[i], parts[i+1] = "false", "", ""
+		}
+	}
 }
 
 // renderEach renders an each() loop
@@ -866,7 +1826,7 @@ func parseNumberForGuard(value string) interface{} {
 func (r *Renderer) renderEach(ctx *NodeContext, e *dst.Each) error {
 	// Evaluate the list expression to get the values
 	vars := ctx.Stack.All()
-	eval, err := expression.NewEvaluator(vars)
+	eval, err := expression.NewEvaluatorWithContext(vars, r.funcs)
 	if err != nil {
 		return err
 	}
@@ -879,30 +1839,35 @@ func (r *Renderer) renderEach(ctx *NodeContext, e *dst.Each) error {
 
 	// Get the list values - range returns "1, 2, 3"
 	listStr := result.String()
-	splitValues := strings.Split(listStr, ",")
-	values := make([]string, len(splitValues))
 
-	// Split by comma to get individual values
-	for i, v := range splitValues {
-		values[i] = strings.TrimSpace(v)
+	// A bare number iterates an implicit range, each(3, ...) is each(range(3), ...)
+	if _, _, ok := parseNumberForGuard(listStr); ok {
+		listStr = r.funcs.Range(listStr)
+	}
+	// Comma and space separated lists are split like extract() does
+	n, _ := strconv.Atoi(functions.Length(listStr))
+	values := make([]string, n)
+	for i := range values {
+		values[i] = functions.Extract(listStr, strconv.Itoa(i+1))
 	}
 
-	// For each value, render the children with @value set
-	for _, val := range values {
-		// Update the loop variable in the current scope
-		// Don't push a new frame - just update the variable
-		oldVal, hadOld := ctx.Stack.Get(e.VarName)
-		ctx.Stack.Set(e.VarName, val)
-
-		// Render children
-		if err := r.renderNodes(ctx, nil, "", e.Children); err != nil {
+	// For each value, render the children with @value, @key and @index set.
+	// The variables are bound in the current scope, a new frame would
+	// change the indentation of the output.
+	for i, val := range values {
+		index := strconv.Itoa(i + 1)
+		restore := ctx.Stack.Bind(map[string]string{
+			e.VarName: val,
+			"key":     index,
+			"index":   index,
+		})
+
+		// Like a mixin call, nested rules are scoped under the caller
+		err := r.expandRuleset(ctx, e.Children)
+		restore()
+		if err != nil {
 			return err
 		}
-
-		// Restore old value if it existed
-		if hadOld {
-			ctx.Stack.Set(e.VarName, oldVal)
-		}
 	}
 
 	return nil
@@ -0,0 +1,74 @@
+package renderer
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// gridLESS generates a grid of n columns with a recursive mixin
+func gridLESS(n int) string {
+	return fmt.Sprintf(`@columns: %d;
+
+.col(@i) when (@i > 0) {
+  .col-@{i} {
+    width: percentage((@i / @columns));
+  }
+  .col(@i - 1);
+}
+
+.grid {
+  .col(@columns);
+}
+`, n)
+}
+
+func renderGrid(tb testing.TB, file *dst.File) string {
+	css, err := NewRenderer().Render(file)
+	require.NoError(tb, err)
+	return css
+}
+
+func TestRenderGrid(t *testing.T) {
+	allocs := make(map[int]float64)
+	for _, n := range []int{12, 60} {
+		file, err := dst.NewParser(strings.NewReader(gridLESS(n))).Parse()
+		require.NoError(t, err)
+
+		css := renderGrid(t, file)
+		require.Equal(t, n, strings.Count(css, "width:"))
+		require.Contains(t, css, fmt.Sprintf(".grid .col-%d {\n  width: 100%%;\n}", n))
+		require.Contains(t, css, ".grid .col-1 {")
+
+		allocs[n] = testing.AllocsPerRun(5, func() {
+			renderGrid(t, file)
+		})
+		require.Less(t, allocs[n], float64(200*n), "allocations per column")
+	}
+
+	// Recursion depth must not make each expansion more expensive
+	require.Less(t, allocs[60], 6*allocs[12])
+}
+
+func benchmarkGrid(b *testing.B, n int) {
+	file, err := dst.NewParser(strings.NewReader(gridLESS(n))).Parse()
+	require.NoError(b, err)
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		renderGrid(b, file)
+	}
+}
+
+// BenchmarkRenderGrid12 renders a 12-column recursive grid
+func BenchmarkRenderGrid12(b *testing.B) {
+	benchmarkGrid(b, 12)
+}
+
+// BenchmarkRenderGrid60 renders a 60-step recursive grid
+func BenchmarkRenderGrid60(b *testing.B) {
+	benchmarkGrid(b, 60)
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/internal/strings"
 )
 
 func TestEvaluateGuard(t *testing.T) {
@@ -45,6 +46,20 @@ func TestEvaluateGuard(t *testing.T) {
 			expected:  true,
 			wantErr:   false,
 		},
+		{
+			name:      "less or equal: -2px <= 0",
+			guard:     &dst.Guard{Condition: "(@v <= 0)"},
+			variables: map[string]string{"v": "-2px"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "less syntax: 5px =< 0 should be false",
+			guard:     &dst.Guard{Condition: "(@v =< 0)"},
+			variables: map[string]string{"v": "5px"},
+			expected:  false,
+			wantErr:   false,
+		},
 		{
 			name:      "with units: 10px > 5px",
 			guard:     &dst.Guard{Condition: "(@width > 5px)"},
@@ -52,6 +67,230 @@ func TestEvaluateGuard(t *testing.T) {
 			expected:  true,
 			wantErr:   false,
 		},
+		{
+			name:      "default() outside a default variant is false",
+			guard:     &dst.Guard{Condition: "(default())"},
+			variables: map[string]string{},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "boolean variable comparison",
+			guard:     &dst.Guard{Condition: "(@flag = true)"},
+			variables: map[string]string{"flag": "true"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "length of a list argument",
+			guard:     &dst.Guard{Condition: "(length(@list) > 2)"},
+			variables: map[string]string{"list": "1px 2px 3px"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "length of a short list argument",
+			guard:     &dst.Guard{Condition: "(length(@list) > 2)"},
+			variables: map[string]string{"list": "a, b"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "extract from a list argument",
+			guard:     &dst.Guard{Condition: "(extract(@list, 2) = b)"},
+			variables: map[string]string{"list": "a b c"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "quoted string with a space",
+			guard:     &dst.Guard{Condition: `(@mode = "dark mode")`},
+			variables: map[string]string{"mode": `"dark mode"`},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "quoted strings are case-sensitive",
+			guard:     &dst.Guard{Condition: `(@mode = "Dark Mode")`},
+			variables: map[string]string{"mode": `"dark mode"`},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "keywords are case-insensitive",
+			guard:     &dst.Guard{Condition: "(@kw = Bold)"},
+			variables: map[string]string{"kw": "bold"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "quoted string equals keyword",
+			guard:     &dst.Guard{Condition: `(@kw = "bold")`},
+			variables: map[string]string{"kw": "bold"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "short hex equals color keyword",
+			guard:     &dst.Guard{Condition: "(@c = white)"},
+			variables: map[string]string{"c": "#fff"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "rgb equals long hex",
+			guard:     &dst.Guard{Condition: "(@c = #ffffff)"},
+			variables: map[string]string{"c": "rgb(255, 255, 255)"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "hex case is ignored",
+			guard:     &dst.Guard{Condition: "(@c = #FFF)"},
+			variables: map[string]string{"c": "#ffffff"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "different colors are not equal",
+			guard:     &dst.Guard{Condition: "(@c = white)"},
+			variables: map[string]string{"c": "#eee"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "important value compares as its number",
+			guard:     &dst.Guard{Condition: "(@v > 4)"},
+			variables: map[string]string{"v": "5px !important"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "stray important in the condition is ignored",
+			guard:     &dst.Guard{Condition: "(@v = 5px !important)"},
+			variables: map[string]string{"v": "5px"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "string inequality",
+			guard:     &dst.Guard{Condition: `(@mode != "dark mode")`},
+			variables: map[string]string{"mode": "light"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "percentage: 60% > 50%",
+			guard:     &dst.Guard{Condition: "(@w > 50%)"},
+			variables: map[string]string{"w": "60%"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "percentage: 40% > 50% should be false",
+			guard:     &dst.Guard{Condition: "(@w > 50%)"},
+			variables: map[string]string{"w": "40%"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "percentage equality: 50% = 50%",
+			guard:     &dst.Guard{Condition: "(@w = 50%)"},
+			variables: map[string]string{"w": "50%"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "pixels: 768px >= 768px",
+			guard:     &dst.Guard{Condition: "(@bp >= 768px)"},
+			variables: map[string]string{"bp": "768px"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "ems: 1.5em < 2em",
+			guard:     &dst.Guard{Condition: "(@size < 2em)"},
+			variables: map[string]string{"size": "1.5em"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "times: 300ms > 200ms",
+			guard:     &dst.Guard{Condition: "(@d > 200ms)"},
+			variables: map[string]string{"d": "300ms"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "times convert: 1s > 500ms",
+			guard:     &dst.Guard{Condition: "(@d > 500ms)"},
+			variables: map[string]string{"d": "1s"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "times convert: 0.2s < 300ms",
+			guard:     &dst.Guard{Condition: "(@d < 300ms)"},
+			variables: map[string]string{"d": "0.2s"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "angles convert: 0.5turn = 180deg",
+			guard:     &dst.Guard{Condition: "(@a = 180deg)"},
+			variables: map[string]string{"a": "0.5turn"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "lengths convert: 1in = 96px",
+			guard:     &dst.Guard{Condition: "(@w = 96px)"},
+			variables: map[string]string{"w": "1in"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "lengths convert: 2.54cm = 1in",
+			guard:     &dst.Guard{Condition: "(@w = 1in)"},
+			variables: map[string]string{"w": "2.54cm"},
+			expected:  true,
+			wantErr:   false,
+		},
+		{
+			name:      "lengths convert: 10mm > 1in should be false",
+			guard:     &dst.Guard{Condition: "(@w > 1in)"},
+			variables: map[string]string{"w": "10mm"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "kinds differ: 1s = 1px should be false",
+			guard:     &dst.Guard{Condition: "(@d = 1px)"},
+			variables: map[string]string{"d": "1s"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "kinds differ: 1deg = 1px should be false",
+			guard:     &dst.Guard{Condition: "(1deg = 1px)"},
+			variables: map[string]string{},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "kinds differ: 2rem > 1em should be false",
+			guard:     &dst.Guard{Condition: "(@a > 1em)"},
+			variables: map[string]string{"a": "2rem"},
+			expected:  false,
+			wantErr:   false,
+		},
+		{
+			name:      "unitless compares with any kind: 10px = 10",
+			guard:     &dst.Guard{Condition: "(@w = 10)"},
+			variables: map[string]string{"w": "10px"},
+			expected:  true,
+			wantErr:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -76,3 +315,29 @@ func TestEvaluateGuard(t *testing.T) {
 		})
 	}
 }
+
+func TestGuardCacheIsPerRender(t *testing.T) {
+	r := NewRenderer()
+
+	file, err := dst.NewParser(strings.NewReader(".m(@a) when (@a > 1) { width: @a; }\n.x { .m(2); }\n")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Render(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.guards) != 1 {
+		t.Errorf("guards after render = %d, want 1", len(r.guards))
+	}
+
+	file, err = dst.NewParser(strings.NewReader(".y { width: 1px; }\n")).Parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Render(file); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.guards) != 0 {
+		t.Errorf("guards after render without guards = %d, want 0", len(r.guards))
+	}
+}
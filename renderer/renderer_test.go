@@ -0,0 +1,64 @@
+package renderer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+func TestRenderDeterministic(t *testing.T) {
+	input := `@breakpoint: 768px;
+@theme: { color: red; };
+
+.base { color: blue; }
+.alt { border: 0; }
+.a:extend(.base, .alt) { margin: 0; }
+.b { &:extend(.base); &:extend(.alt); }
+.c:extend(.a) { padding: 0; }
+
+.mixin(@color) {
+  background: lighten(darken(@color, 10%), 20%);
+  border-color: mix(spin(@color, 30), fade(@color, 50%), 50%);
+}
+
+.card {
+  .mixin(#336699);
+  @media (min-width: @breakpoint) { width: 50%; }
+  @media (max-width: 1024px) { width: 75%; }
+  @media (min-width: 768px) { height: auto; }
+  .title { @theme(); }
+}
+`
+
+	var want string
+	for i := 0; i < 50; i++ {
+		file, err := dst.NewParser(strings.NewReader(input)).Parse()
+		require.NoError(t, err)
+
+		css, err := NewRenderer().Render(file)
+		require.NoError(t, err)
+
+		if i == 0 {
+			want = css
+			continue
+		}
+		require.Equal(t, want, css, "render %d differs", i)
+	}
+}
+
+func TestMixinPath(t *testing.T) {
+	tests := map[string]string{
+		".m":              ".m",
+		"#ns.m":           "#ns > .m",
+		"#ns #inner.m":    "#ns > #inner > .m",
+		"#ns#inner.m":     "#ns > #inner > .m",
+		"#ns>#inner > .m": "#ns > #inner > .m",
+		".a\\.b":          ".a\\.b",
+		"#ns > .m-1\\.5":  "#ns > .m-1\\.5",
+	}
+	for name, want := range tests {
+		require.Equal(t, want, mixinPath(name), name)
+	}
+}
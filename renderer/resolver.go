@@ -1,13 +1,17 @@
 package renderer
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"regexp"
+	"unicode"
 
 	"github.com/titpetric/lessgo/dst"
 	"github.com/titpetric/lessgo/evaluator"
 	"github.com/titpetric/lessgo/expression"
+	"github.com/titpetric/lessgo/expression/functions"
 	"github.com/titpetric/lessgo/internal/strings"
 )
 
@@ -18,13 +22,21 @@ var (
 
 // Resolver resolves variables and expressions in declarations for rendering
 type Resolver struct {
-	file *dst.File
+	file  *dst.File
+	funcs *functions.Context
 }
 
 // NewResolver creates a new resolver from a file's variable stack
 func NewResolver(file *dst.File) *Resolver {
+	return NewResolverWithContext(file, nil)
+}
+
+// NewResolverWithContext creates a new resolver that evaluates functions
+// with the settings of funcs
+func NewResolverWithContext(file *dst.File, funcs *functions.Context) *Resolver {
 	return &Resolver{
-		file: file,
+		file:  file,
+		funcs: funcs,
 	}
 }
 
@@ -32,10 +44,46 @@ func NewResolver(file *dst.File) *Resolver {
 func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 	value = strings.TrimSpace(value)
 
+	// A trailing !important isn't part of the expression, it's reattached
+	// to the result. A variable holding an important value keeps its own.
+	if rest, ok := cutImportant(value); ok {
+		resolved, err := r.ResolveValue(stack, rest)
+		if err != nil {
+			return "", err
+		}
+		if _, ok := cutImportant(resolved); ok {
+			return resolved, nil
+		}
+		return resolved + " !important", nil
+	}
+
 	if strings.HasPrefix(value, "#") {
 		return value, nil
 	}
 
+	// A bare reference to an already resolved variable is used verbatim,
+	// so escaped values like ~"(min-width: 768px)" aren't evaluated again
+	if name, ok := variableName(value); ok {
+		if v, ok := stack.Get(name); ok && !strings.Contains(v, "@") {
+			return v, nil
+		}
+	}
+
+	// Slash-separated values like grid lines (1 / 3) or ratios (16 / 9) aren't division
+	if isSlashList(value) {
+		return value, nil
+	}
+
+	// Interpolate quoted strings and unwrap ~"..." escapes
+	if strings.ContainsAny(value, "\"'") {
+		escaped := isEscapedString(value)
+		value = r.resolveStrings(stack, value)
+		if escaped {
+			// A fully escaped value is used verbatim
+			return value, nil
+		}
+	}
+
 	// Strip outer parentheses if present (they're just for grouping)
 	if strings.HasPrefix(value, "(") && strings.HasSuffix(value, ")") {
 		// Check that the closing paren matches the opening one
@@ -53,13 +101,16 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 				}
 			}
 		}
-		if allWrapped && depth == 0 {
+		// A media feature like (max-width: 400px) keeps its parentheses
+		if allWrapped && depth == 0 && !strings.Contains(value, ":") {
 			value = value[1 : len(value)-1]
 			value = strings.TrimSpace(value)
 		}
 	}
 
-	// First, substitute variables
+	// First, substitute variables. A variable can hold a list, so function
+	// arguments are only counted in values without variables.
+	literal := !strings.Contains(value, "@")
 	value = r.substituteVariables(stack, value)
 
 	// Skip evaluation if it contains CSS-only functions (these should pass through)
@@ -69,7 +120,7 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 
 	vars := stack.All()
 
-	eval, err := expression.NewEvaluator(vars)
+	eval, err := expression.NewEvaluatorWithContext(vars, r.funcs)
 	if err != nil {
 		return "", err
 	}
@@ -81,6 +132,9 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 		if err == nil {
 			return v.String(), nil
 		}
+		if isReportedError(err, literal) {
+			return "", err
+		}
 		// If evaluation fails, fall through to tokenization
 	}
 
@@ -91,7 +145,8 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 		return fmt.Sprint(v), err
 	}
 
-	isExpression := evaluator.IsExpression(tokens)
+	// Operators between quoted strings are left alone, e.g. "a" + "b"
+	isExpression := evaluator.IsExpression(tokens) && !hasQuotedToken(tokens)
 
 	parts := []string{}
 	for _, tok := range tokens {
@@ -99,6 +154,8 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 			v, err := eval.Eval(tok.Text)
 			if err == nil {
 				tok.Text = fmt.Sprint(v)
+			} else if isReportedError(err, literal) {
+				return "", err
 			}
 		}
 		parts = append(parts, tok.Text)
@@ -133,23 +190,265 @@ func (r *Resolver) ResolveValue(stack *Stack, value string) (string, error) {
 	result := strings.Join(parts, delimiter)
 
 	// Now evaluate any embedded functions in the result
-	result = r.evaluateEmbeddedFunctions(eval, result)
+	result, err = r.evaluateEmbeddedFunctions(eval, result, literal)
+	if err != nil {
+		return "", err
+	}
 
 	return result, nil
 }
 
+// maxInterpolationDepth caps nested interpolation like @{@{name}}
+const maxInterpolationDepth = 8
+
 // InterpolateVariables replaces @{varname} patterns with their values from the stack
-// This handles LESS variable interpolation syntax like .@{prefix} and @{prop}: value
+// This handles LESS variable interpolation syntax like .@{prefix} and @{prop}: value.
+// Nested interpolation (@{@{ptr}}) resolves from the inside out until the text is stable.
 func (r *Resolver) InterpolateVariables(stack *Stack, text string) string {
-	// Use cached regex to avoid recompiling on every call
-	return varInterpolateRegex.ReplaceAllStringFunc(text, func(match string) string {
-		// Extract variable name from @{name}
-		varName := match[2 : len(match)-1] // Remove @{ and }
-		if val, ok := stack.Get(varName); ok {
-			return val
+	for depth := 0; depth < maxInterpolationDepth && strings.Contains(text, "@{"); depth++ {
+		// Use cached regex to avoid recompiling on every call
+		next := varInterpolateRegex.ReplaceAllStringFunc(text, func(match string) string {
+			// Extract variable name from @{name}
+			varName := match[2 : len(match)-1] // Remove @{ and }
+			if val, ok := stack.Get(varName); ok {
+				return unquote(val)
+			}
+			return match // If variable not found, return original
+		})
+		if next == text {
+			break
 		}
-		return match // If variable not found, return original
-	})
+		text = next
+	}
+	return text
+}
+
+// resolveStrings interpolates @{var} inside quoted strings and removes the quotes
+// from escaped ~"..." strings, e.g. url(~"@{base}/x.png") becomes url(/img/x.png)
+func (r *Resolver) resolveStrings(stack *Stack, value string) string {
+	var buf strings.Builder
+	for i := 0; i < len(value); i++ {
+		quote := value[i]
+		if quote != '"' && quote != '\'' {
+			buf.WriteByte(quote)
+			continue
+		}
+
+		// Find the closing quote, respecting escapes
+		end := i + 1
+		for end < len(value) && value[end] != quote {
+			if value[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(value) {
+			buf.WriteString(value[i:])
+			break
+		}
+
+		content := r.InterpolateVariables(stack, value[i+1:end])
+
+		if i > 0 && value[i-1] == '~' {
+			// Escaped string: drop the "~" and the quotes
+			out := buf.String()
+			buf.Reset()
+			buf.WriteString(out[:len(out)-1])
+			buf.WriteString(content)
+		} else {
+			buf.WriteByte(quote)
+			buf.WriteString(content)
+			buf.WriteByte(quote)
+		}
+		i = end
+	}
+	return buf.String()
+}
+
+// ResolveMediaQuery substitutes @var references and @{var} interpolation in an
+// at-rule prelude like "@media @tablet and (orientation: landscape)"
+func (r *Resolver) ResolveMediaQuery(stack *Stack, condition string) string {
+	condition = r.InterpolateVariables(stack, condition)
+
+	// Keep the at-rule keyword itself, e.g. "@media"
+	keyword := ""
+	if strings.HasPrefix(condition, "@") {
+		i := 1
+		for i < len(condition) && isVarChar(rune(condition[i])) {
+			i++
+		}
+		keyword, condition = condition[:i], condition[i:]
+	}
+
+	var buf strings.Builder
+	buf.WriteString(keyword)
+	for i := 0; i < len(condition); i++ {
+		if condition[i] != '@' {
+			buf.WriteByte(condition[i])
+			continue
+		}
+		j := i + 1
+		for j < len(condition) && isVarChar(rune(condition[j])) {
+			j++
+		}
+		val, ok := stack.Get(condition[i+1 : j])
+		if !ok {
+			buf.WriteByte(condition[i])
+			continue
+		}
+		buf.WriteString(unquote(val))
+		i = j - 1
+	}
+	// Collapse whitespace so equivalent conditions compare equal when merging
+	return normalizeMediaQueryList(strings.Join(strings.Fields(buf.String()), " "))
+}
+
+// normalizeMediaQueryList formats a comma-separated query list as "a, b"
+func normalizeMediaQueryList(condition string) string {
+	if !strings.Contains(condition, ",") {
+		return condition
+	}
+	var buf strings.Builder
+	depth := 0
+	start := 0
+	for i := 0; i <= len(condition); i++ {
+		if i < len(condition) {
+			switch condition[i] {
+			case '(':
+				depth++
+				continue
+			case ')':
+				depth--
+				continue
+			case ',':
+				if depth > 0 {
+					continue
+				}
+			default:
+				continue
+			}
+		}
+		part := strings.TrimSpace(condition[start:i])
+		if start > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(part)
+		start = i + 1
+	}
+	return buf.String()
+}
+
+// isEscapedString checks if the whole value is a single ~"..." escaped string
+func isEscapedString(value string) bool {
+	if len(value) < 3 || value[0] != '~' {
+		return false
+	}
+	quote := value[1]
+	if (quote != '"' && quote != '\'') || value[len(value)-1] != quote {
+		return false
+	}
+	return !strings.Contains(value[2:len(value)-1], string(quote))
+}
+
+// isEscapedValue checks if the value is a single ~"..." or e("...") escape,
+// which is output as its raw contents
+func isEscapedValue(value string) bool {
+	value = strings.TrimSpace(value)
+	if inner, ok := strings.CutPrefix(value, "e("); ok && strings.HasSuffix(inner, ")") {
+		inner = inner[:len(inner)-1]
+		return len(inner) >= 2 && (inner[0] == '"' || inner[0] == '\'') && isEscapedString("~"+inner)
+	}
+	return isEscapedString(value)
+}
+
+// isSlashList checks if the value is a slash-separated list of numbers and
+// keywords, e.g. "1 / 3", "12px / 1.5" or "span 2 / main-end". A slash
+// between literals is kept as a separator, with or without units. Values
+// with variables, parentheses or other operators are left to the expression
+// evaluator, which divides.
+func isSlashList(value string) bool {
+	if !strings.Contains(value, "/") {
+		return false
+	}
+	for _, part := range strings.Split(value, "/") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			return false
+		}
+		for _, field := range fields {
+			if !isDimension(field) && !isKeyword(field) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isUnitlessNumber checks if the field is a plain number like 2, -1 or 1.5
+func isUnitlessNumber(field string) bool {
+	field = strings.TrimPrefix(field, "-")
+	if field == "" || field == "." {
+		return false
+	}
+	for _, ch := range field {
+		if (ch < '0' || ch > '9') && ch != '.' {
+			return false
+		}
+	}
+	return true
+}
+
+// isDimension checks if the field is a number with an optional unit like 2, 1.5em or 50%
+func isDimension(field string) bool {
+	end := len(field)
+	for end > 0 && (unicode.IsLetter(rune(field[end-1])) || field[end-1] == '%') {
+		end--
+	}
+	return isUnitlessNumber(field[:end])
+}
+
+// isKeyword checks if the field is a CSS identifier like span or main-start
+func isKeyword(field string) bool {
+	if field == "" || !unicode.IsLetter(rune(field[0])) {
+		return false
+	}
+	for _, ch := range field {
+		if !unicode.IsLetter(ch) && !unicode.IsDigit(ch) && ch != '-' && ch != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// variableName returns the name of a bare variable reference like @name
+func variableName(value string) (string, bool) {
+	if len(value) < 2 || value[0] != '@' {
+		return "", false
+	}
+	for _, ch := range value[1:] {
+		if !isVarChar(ch) {
+			return "", false
+		}
+	}
+	return value[1:], true
+}
+
+// unquote removes surrounding quotes from a string value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// hasQuotedToken reports whether any token is a quoted string
+func hasQuotedToken(tokens []evaluator.Token) bool {
+	for _, tok := range tokens {
+		if tok.Type == evaluator.TokenValue && unquote(tok.Text) != tok.Text {
+			return true
+		}
+	}
+	return false
 }
 
 // isCSSOnlyFunction checks if the value contains CSS-only functions that we shouldn't evaluate
@@ -167,31 +466,58 @@ func isCSSOnlyFunction(value string) bool {
 
 // substituteVariables replaces @variable with their values
 func (r *Resolver) substituteVariables(stack *Stack, value string) string {
-	// Simple variable substitution
-	for {
-		idx := strings.Index(value, "@")
+	// Simple variable substitution, references that can't be resolved are
+	// left as written and the search continues after them
+	for start := 0; ; {
+		idx := strings.Index(value[start:], "@")
 		if idx == -1 {
 			break
 		}
+		idx += start
+
+		// Variable variables (@@name) use the value of @name as the variable name
+		indirect := idx+1 < len(value) && value[idx+1] == '@'
 
 		// Find the end of the variable name
 		i := idx + 1
+		if indirect {
+			i++
+		}
 		for i < len(value) && (isVarChar(rune(value[i]))) {
 			i++
 		}
 
+		if indirect {
+			ptr, ok := stack.Get(value[idx+2 : i])
+			target := unquote(ptr)
+			if !ok || target == "" || strings.HasPrefix(target, "@") {
+				start = i
+				continue
+			}
+			// Replace @@name with @target and resolve again
+			value = value[:idx] + "@" + target + value[i:]
+			continue
+		}
+
 		if i == idx+1 {
 			// No valid variable name found
-			break
+			start = i
+			continue
 		}
 
 		varName := value[idx+1 : i]
 		if val, ok := stack.Get(varName); ok {
 			// Recursively resolve in case variable references another variable
 			resolved, _ := r.ResolveValue(stack, val)
+			// Negating a negative value, e.g. -@offset with @offset: -5px
+			if idx > 0 && value[idx-1] == '-' && strings.HasPrefix(resolved, "-") && (idx == 1 || strings.ContainsAny(value[idx-2:idx-1], " (*/+-")) {
+				idx--
+				resolved = resolved[1:]
+			}
 			value = value[:idx] + resolved + value[i:]
+			start = idx
 		} else {
-			break
+			start = i
 		}
 	}
 
@@ -257,8 +583,9 @@ func (r *Resolver) containsOperator(value string) bool {
 	return false
 }
 
-// evaluateEmbeddedFunctions evaluates all function calls embedded in a string
-func (r *Resolver) evaluateEmbeddedFunctions(eval *expression.Evaluator, value string) string {
+// evaluateEmbeddedFunctions evaluates all function calls embedded in a
+// string, literal is true when the value had no variables
+func (r *Resolver) evaluateEmbeddedFunctions(eval *expression.Evaluator, value string, literal bool) (string, error) {
 	functions := r.extractFunctionsFromValue(value)
 	result := value
 
@@ -267,10 +594,35 @@ func (r *Resolver) evaluateEmbeddedFunctions(eval *expression.Evaluator, value s
 		if err == nil {
 			// Replace all occurrences of this function call with its result
 			result = strings.ReplaceAll(result, funcCall, v.String())
+		} else if isReportedError(err, literal) {
+			return "", err
 		}
 	}
 
-	return result
+	return result, nil
+}
+
+// isReportedError reports whether err is from a function reading a file,
+// like data-uri() with a missing file, or from a LESS function called with
+// the wrong number of arguments in a literal value, one without variables.
+// Other functions that fail to evaluate are written as given, like CSS
+// functions sharing a name with a LESS function, e.g. rgb(0 0 0 / 50%).
+func isReportedError(err error, literal bool) bool {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return true
+	}
+	var argErr *expression.ArgumentError
+	return literal && errors.As(err, &argErr) && !cssFunctions[argErr.Function]
+}
+
+// cssFunctions are CSS functions sharing a name with a LESS function, which
+// are written as given when the arguments don't fit the LESS function
+var cssFunctions = map[string]bool{
+	"rgb": true, "rgba": true, "hsl": true, "hsla": true, "color": true,
+	"min": true, "max": true, "clamp": true, "round": true, "mod": true,
+	"sin": true, "cos": true, "tan": true, "asin": true, "acos": true,
+	"atan": true, "pow": true, "sqrt": true, "abs": true,
 }
 
 var (
@@ -280,6 +632,10 @@ var (
 
 	// Pre-cached search strings (funcName + "(") to avoid repeated allocation
 	evaluableEmbeddedFuncSearches map[string]struct{}
+
+	// Search strings for all registered functions, including the type
+	// checks, used to evaluate guard conditions
+	registeredFuncSearches map[string]struct{}
 )
 
 func initEvaluableFuncNames() {
@@ -293,7 +649,7 @@ func initEvaluableFuncNames() {
 		// Type checking functions that return booleans
 		"isnumber": true, "isstring": true, "iscolor": true, "iskeyword": true,
 		"isurl": true, "ispixel": true, "isem": true, "ispercentage": true,
-		"isunit": true, "isruleset": true, "islist": true, "isdefined": true,
+		"isunit": true, "isunitless": true, "is-unitless": true, "isruleset": true, "islist": true, "isdefined": true,
 		"isnumberfunction": true, "isstringfunction": true, "iscolorfunction": true,
 		"iskeywordfunction": true, "isurlfunc": true, "ispixelfunction": true,
 		"isemfunction": true, "ispercentagefunction": true, "isunitfunction": true,
@@ -305,8 +661,10 @@ func initEvaluableFuncNames() {
 	allFuncNames := expression.GetRegisteredFunctionNames()
 	evaluableEmbeddedFuncNames = make([]string, 0, len(allFuncNames))
 	evaluableEmbeddedFuncSearches = make(map[string]struct{}, len(allFuncNames))
+	registeredFuncSearches = make(map[string]struct{}, len(allFuncNames))
 
 	for _, name := range allFuncNames {
+		registeredFuncSearches[name+"("] = struct{}{}
 		if !excludeFuncs[name] {
 			evaluableEmbeddedFuncNames = append(evaluableEmbeddedFuncNames, name)
 			// Pre-cache the search string to avoid repeated allocation
@@ -316,10 +674,14 @@ func initEvaluableFuncNames() {
 }
 
 // extractFunctionsFromValue extracts all function calls from a string value
-// Uses single-pass algorithm with pre-cached search strings
 func (r *Resolver) extractFunctionsFromValue(value string) []string {
 	initEvaluableFuncNames()
+	return extractFunctionCalls(value, evaluableEmbeddedFuncSearches)
+}
 
+// extractFunctionCalls extracts the calls of the functions in searches from
+// a string value. Uses single-pass algorithm with pre-cached search strings.
+func extractFunctionCalls(value string, searches map[string]struct{}) []string {
 	// Quick pre-check: if value doesn't contain '(', no functions possible
 	if !strings.Contains(value, "(") {
 		return nil
@@ -348,7 +710,6 @@ func (r *Resolver) extractFunctionsFromValue(value string) []string {
 
 	// Single pass through value, matching function patterns
 	var functions []string
-	searches := evaluableEmbeddedFuncSearches
 
 	for i := 0; i < len(value); i++ {
 		// Skip if inside quotes
@@ -3,6 +3,7 @@ package renderer
 import (
 	"testing"
 
+	"github.com/stretchr/testify/require"
 	"github.com/titpetric/lessgo/evaluator"
 )
 
@@ -45,6 +46,27 @@ func TestResolveValue(t *testing.T) {
 			expected:  "15px",
 			wantErr:   false,
 		},
+		{
+			name:      "important after an expression",
+			value:     "(@base * 2) !important",
+			variables: map[string]string{"base": "10px"},
+			expected:  "20px !important",
+			wantErr:   false,
+		},
+		{
+			name:      "important carried by a variable",
+			value:     "@size",
+			variables: map[string]string{"size": "5px !important"},
+			expected:  "5px !important",
+			wantErr:   false,
+		},
+		{
+			name:      "important is not repeated",
+			value:     "@size !important",
+			variables: map[string]string{"size": "5px !important"},
+			expected:  "5px !important",
+			wantErr:   false,
+		},
 		{
 			name:      "CSS grid minmax passthrough",
 			value:     "repeat(auto-fit, minmax(250px, 1fr))",
@@ -59,6 +81,142 @@ func TestResolveValue(t *testing.T) {
 			expected:  "minmax(100px, 1fr)",
 			wantErr:   false,
 		},
+		{
+			name:      "escaped interpolated url",
+			value:     `url(~"@{base}/x.png")`,
+			variables: map[string]string{"base": `"/img"`},
+			expected:  "url(/img/x.png)",
+			wantErr:   false,
+		},
+		{
+			name:      "quoted interpolated url",
+			value:     `url("@{base}/x.png")`,
+			variables: map[string]string{"base": `"/img"`},
+			expected:  `url("/img/x.png")`,
+			wantErr:   false,
+		},
+		{
+			name:      "escaped string value",
+			value:     `~"@{w} + 10px"`,
+			variables: map[string]string{"w": "100%"},
+			expected:  "100% + 10px",
+			wantErr:   false,
+		},
+		{
+			name:      "escaped value is not evaluated again",
+			value:     "@query",
+			variables: map[string]string{"query": "(min-width: 768px)"},
+			expected:  "(min-width: 768px)",
+			wantErr:   false,
+		},
+		{
+			name:      "escaped ratio is not divided",
+			value:     "@ratio",
+			variables: map[string]string{"ratio": "16/9"},
+			expected:  "16/9",
+			wantErr:   false,
+		},
+		{
+			name:      "minus before a variable is a sign",
+			value:     "-@b",
+			variables: map[string]string{"b": "2px"},
+			expected:  "-2px",
+			wantErr:   false,
+		},
+		{
+			name:      "space-separated variables are not subtracted",
+			value:     "@a @b",
+			variables: map[string]string{"a": "1px", "b": "2px"},
+			expected:  "1px 2px",
+			wantErr:   false,
+		},
+		{
+			name:      "space-separated variables with a negative",
+			value:     "@a -@b",
+			variables: map[string]string{"a": "1px", "b": "2px"},
+			expected:  "1px -2px",
+			wantErr:   false,
+		},
+		{
+			name:      "negative transform arguments",
+			value:     "translate(-50%, -50%)",
+			variables: map[string]string{},
+			expected:  "translate(-50%, -50%)",
+			wantErr:   false,
+		},
+		{
+			name:      "grid lines are not divided",
+			value:     "1 / 3",
+			variables: map[string]string{},
+			expected:  "1 / 3",
+			wantErr:   false,
+		},
+		{
+			name:      "grid area keeps all slashes",
+			value:     "1 / 2 / 3 / 4",
+			variables: map[string]string{},
+			expected:  "1 / 2 / 3 / 4",
+			wantErr:   false,
+		},
+		{
+			name:      "grid span with keywords",
+			value:     "span 2 / main-end",
+			variables: map[string]string{},
+			expected:  "span 2 / main-end",
+			wantErr:   false,
+		},
+		// A slash between literal values is a separator, with or without
+		// units, like grid lines or font: 12px / 1.5. Division applies when
+		// a variable is involved or the expression is in parentheses.
+		{
+			name:      "unitless literals are not divided",
+			value:     "16 / 2",
+			variables: map[string]string{},
+			expected:  "16 / 2",
+			wantErr:   false,
+		},
+		{
+			name:      "literals with units are not divided",
+			value:     "10px / 2",
+			variables: map[string]string{},
+			expected:  "10px / 2",
+			wantErr:   false,
+		},
+		{
+			name:      "parenthesized literals are divided",
+			value:     "(10px / 2)",
+			variables: map[string]string{},
+			expected:  "5px",
+			wantErr:   false,
+		},
+		{
+			name:      "division with units",
+			value:     "@w / 2",
+			variables: map[string]string{"w": "10px"},
+			expected:  "5px",
+			wantErr:   false,
+		},
+		{
+			name:      "single number",
+			value:     "10",
+			variables: map[string]string{},
+			expected:  "10",
+			wantErr:   false,
+		},
+		{
+			name:      "url contents are not evaluated",
+			value:     "10px url(img/a-2/b*3.png) no-repeat",
+			variables: map[string]string{},
+			expected:  "10px url(img/a-2/b*3.png) no-repeat",
+			wantErr:   false,
+		},
+		{
+			name:      "data uri keeps its commas",
+			value:     "url(data:image/svg+xml;charset=utf8,%3Csvg%3E), none",
+			variables: map[string]string{},
+			expected:  "url(data:image/svg+xml;charset=utf8,%3Csvg%3E), none",
+			wantErr:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,3 +316,87 @@ func TestTokenize(t *testing.T) {
 		})
 	}
 }
+
+func TestInterpolateVariablesNested(t *testing.T) {
+	resolver := NewResolver(nil)
+	stack := NewStack()
+	stack.Set("prop", `"color"`)
+	stack.Set("ptr", `"prop"`)
+	stack.Set("ptr2", `"ptr"`)
+	stack.Set("loop", `"@{loop}"`)
+
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+	}{
+		{"flat", "@{prop}", "color"},
+		{"one level", "@{@{ptr}}", "color"},
+		{"two levels", "@{@{@{ptr2}}}-x", "color-x"},
+		{"unknown", "@{missing}", "@{missing}"},
+		{"depth cap", "@{loop}", "@{loop}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, resolver.InterpolateVariables(stack, tt.text))
+		})
+	}
+}
+
+func TestSubstituteVariablesIndirect(t *testing.T) {
+	resolver := NewResolver(nil)
+	stack := NewStack()
+	stack.Set("primary", "red")
+	stack.Set("name", `"primary"`)
+	stack.Set("gap", "10px")
+
+	tests := []struct {
+		value    string
+		expected string
+	}{
+		{"@@name", "red"},
+		{"@@name @gap", "red 10px"},
+		{"@@missing @gap", "@@missing 10px"},
+		{"@missing @@name @gap", "@missing red 10px"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			require.Equal(t, tt.expected, resolver.substituteVariables(stack, tt.value))
+		})
+	}
+}
+
+func TestResolveMediaQuery(t *testing.T) {
+	resolver := NewResolver(nil)
+	stack := NewStack()
+	stack.Set("bp", "768px")
+	stack.Set("screen", "only screen")
+	stack.Set("tablet", `"(min-width: 768px)"`)
+	stack.Set("phone", "(max-width: 400px)")
+	stack.Set("content", "48rem")
+	stack.Set("wide", "calc(48rem + 10px)")
+
+	tests := []struct {
+		condition string
+		expected  string
+	}{
+		{"@media only screen and (max-width: @bp)", "@media only screen and (max-width: 768px)"},
+		{"@media @screen  and (max-width: @{bp})", "@media only screen and (max-width: 768px)"},
+		{"@media not all and (monochrome)", "@media not all and (monochrome)"},
+		{"@media not print,  @tablet", "@media not print, (min-width: 768px)"},
+		{"@media @tablet and (orientation: portrait)", "@media (min-width: 768px) and (orientation: portrait)"},
+		{"@media screen and @phone, print", "@media screen and (max-width: 400px), print"},
+		{"@media (min-width: @content)", "@media (min-width: 48rem)"},
+		{"@media (min-width: calc(@content + 1px))", "@media (min-width: calc(48rem + 1px))"},
+		{"@media (min-width: @wide)", "@media (min-width: calc(48rem + 10px))"},
+		{"@media (400px <= width <= @content)", "@media (400px <= width <= 48rem)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.condition, func(t *testing.T) {
+			require.Equal(t, tt.expected, resolver.ResolveMediaQuery(stack, tt.condition))
+		})
+	}
+}
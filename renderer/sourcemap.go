@@ -0,0 +1,175 @@
+package renderer
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// Rules and declarations are preceded by a position marker while rendering
+// with Options.SourceMapInline, e.g. "\x0112\x02" for line 12. The markers
+// are carried through output formatting and removed by extractPositions.
+const (
+	positionStart = '\x01'
+	positionEnd   = '\x02'
+)
+
+// sourceMap is a version 3 source map
+type sourceMap struct {
+	Version  int      `json:"version"`
+	Sources  []string `json:"sources"`
+	Names    []string `json:"names"`
+	Mappings string   `json:"mappings"`
+}
+
+// mapping maps a position in the output to a line of the source file.
+// Lines and columns are 0-based, columns count UTF-16 code units.
+type mapping struct {
+	line, column int
+	sourceLine   int
+}
+
+// writePosition writes the marker for the source line of a rule or declaration
+func (r *Renderer) writePosition(buf *strings.Builder, line int) {
+	if !r.options.SourceMapInline || line <= 0 {
+		return
+	}
+	buf.WriteByte(positionStart)
+	buf.WriteString(strconv.Itoa(line))
+	buf.WriteByte(positionEnd)
+}
+
+// appendSourceMap removes the position markers from css and appends the
+// source map they describe as a sourceMappingURL comment
+func (r *Renderer) appendSourceMap(css string) (string, error) {
+	css, mappings := extractPositions(css)
+	source := r.options.SourceMapFile
+	if source == "" {
+		source = "input.less"
+	}
+	comment, err := inlineSourceMap(source, mappings)
+	if err != nil {
+		return "", err
+	}
+	if css != "" && !strings.HasSuffix(css, "\n") {
+		css += "\n"
+	}
+	css += comment
+	if !r.options.OmitFinalNewline {
+		css += "\n"
+	}
+	return css, nil
+}
+
+// extractPositions removes the position markers from css and returns the
+// mappings they describe
+func extractPositions(css string) (string, []mapping) {
+	if strings.IndexByte(css, positionStart) == -1 {
+		return css, nil
+	}
+
+	var buf strings.Builder
+	var mappings []mapping
+	line, lineStart := 0, 0
+	for i := 0; i < len(css); i++ {
+		switch ch := css[i]; ch {
+		case '\n':
+			line++
+			buf.WriteByte(ch)
+			lineStart = buf.Len()
+		case positionStart:
+			end := strings.IndexByte(css[i:], positionEnd)
+			if end == -1 {
+				continue
+			}
+			if n, err := strconv.Atoi(css[i+1 : i+end]); err == nil {
+				column := utf16Len(buf.String()[lineStart:])
+				mappings = append(mappings, mapping{line: line, column: column, sourceLine: n - 1})
+			}
+			i += end
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return buf.String(), mappings
+}
+
+// stripPositions removes the position markers from s
+func stripPositions(s string) string {
+	s, _ = extractPositions(s)
+	return s
+}
+
+// utf16Len returns the length of s in UTF-16 code units
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n++
+		if r > 0xFFFF {
+			n++
+		}
+	}
+	return n
+}
+
+// inlineSourceMap returns a sourceMappingURL comment holding the source map
+// of mappings for the source file, base64 encoded
+func inlineSourceMap(source string, mappings []mapping) (string, error) {
+	data, err := json.Marshal(sourceMap{
+		Version:  3,
+		Sources:  []string{source},
+		Names:    []string{},
+		Mappings: encodeMappings(mappings),
+	})
+	if err != nil {
+		return "", err
+	}
+	return "/*# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(data) + " */", nil
+}
+
+// encodeMappings encodes mappings, ordered by output position, as the
+// mappings field of a source map. Each segment holds the output column,
+// source index, source line and source column, relative to the previous one.
+func encodeMappings(mappings []mapping) string {
+	var buf strings.Builder
+	line, column, sourceLine := 0, 0, 0
+	for i, m := range mappings {
+		if m.line > line {
+			buf.WriteString(strings.Repeat(";", m.line-line))
+			line, column = m.line, 0
+		} else if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeVLQ(&buf, m.column-column)
+		writeVLQ(&buf, 0)
+		writeVLQ(&buf, m.sourceLine-sourceLine)
+		writeVLQ(&buf, 0)
+		column, sourceLine = m.column, m.sourceLine
+	}
+	return buf.String()
+}
+
+// base64VLQ holds the digits of the base64 VLQ encoding
+const base64VLQ = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes n in the base64 VLQ encoding of source maps, the sign is
+// the lowest bit and each digit holds 5 bits with a continuation bit
+func writeVLQ(buf *strings.Builder, n int) {
+	v := n << 1
+	if n < 0 {
+		v = -n<<1 | 1
+	}
+	for {
+		digit := v & 31
+		v >>= 5
+		if v > 0 {
+			digit |= 32
+		}
+		buf.WriteByte(base64VLQ[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
@@ -34,6 +34,42 @@ func (s *Stack) Set(name, value string) {
 	s.frames[len(s.frames)-1][name] = value
 }
 
+// Bind sets variables in the current scope and returns a function restoring
+// the previous values. Mixin parameters use it so they don't leak into the
+// caller's scope without adding a frame (frames also drive indentation).
+func (s *Stack) Bind(vars map[string]string) (restore func()) {
+	frame := s.frames[len(s.frames)-1]
+	previous := make(map[string]string, len(vars))
+	missing := make([]string, 0, len(vars))
+	for name, value := range vars {
+		if old, ok := frame[name]; ok {
+			previous[name] = old
+		} else {
+			missing = append(missing, name)
+		}
+		frame[name] = value
+	}
+
+	return func() {
+		for name, old := range previous {
+			frame[name] = old
+		}
+		for _, name := range missing {
+			delete(frame, name)
+		}
+	}
+}
+
+// Local returns a copy of the variables set in the current scope
+func (s *Stack) Local() map[string]string {
+	frame := s.frames[len(s.frames)-1]
+	result := make(map[string]string, len(frame))
+	for k, v := range frame {
+		result[k] = v
+	}
+	return result
+}
+
 // Get retrieves a variable by searching from the current scope up to global scope
 func (s *Stack) Get(name string) (string, bool) {
 	// Search from current scope (top of stack) downward to global scope
@@ -107,3 +107,54 @@ func TestStackAll(t *testing.T) {
 		t.Errorf("All() shadowed var = %s, want 2-local", all["b"])
 	}
 }
+
+func TestStackBind(t *testing.T) {
+	s := NewStack()
+	s.Set("x", "outer")
+
+	restore := s.Bind(map[string]string{"x": "inner", "y": "param"})
+	if val, _ := s.Get("x"); val != "inner" {
+		t.Errorf("Get(x) while bound = %s, want inner", val)
+	}
+
+	restore()
+	if val, _ := s.Get("x"); val != "outer" {
+		t.Errorf("Get(x) after restore = %s, want outer", val)
+	}
+	if _, ok := s.Get("y"); ok {
+		t.Errorf("Get(y) after restore should not exist")
+	}
+}
+
+func TestStackLocal(t *testing.T) {
+	s := NewStack()
+	s.Set("x", "global")
+
+	s.Push()
+	s.Set("y", "local")
+	local := s.Local()
+	s.Pop()
+
+	if len(local) != 1 || local["y"] != "local" {
+		t.Errorf("Local() = %v, want only y", local)
+	}
+
+	// Nested rules bind the block's variables, read through to globals and shadow locally
+	restore := s.Bind(local)
+	s.Push()
+	if val, _ := s.Get("x"); val != "global" {
+		t.Errorf("Get(x) in nested scope = %s, want global", val)
+	}
+	s.Set("y", "shadow")
+	if val, _ := s.Get("y"); val != "shadow" {
+		t.Errorf("Get(y) shadowed = %s, want shadow", val)
+	}
+	s.Pop()
+	if val, _ := s.Get("y"); val != "local" {
+		t.Errorf("Get(y) after nested scope = %s, want local", val)
+	}
+	restore()
+	if _, ok := s.Get("y"); ok {
+		t.Errorf("Get(y) after restore should not exist")
+	}
+}
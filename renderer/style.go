@@ -0,0 +1,441 @@
+package renderer
+
+import (
+	"fmt"
+
+	"github.com/titpetric/lessgo/internal/strings"
+)
+
+// OutputStyle selects how the rendered CSS is laid out
+type OutputStyle string
+
+const (
+	// OutputExpanded puts each declaration on its own line (the default)
+	OutputExpanded OutputStyle = "expanded"
+	// OutputCompact puts each rule on a single line
+	OutputCompact OutputStyle = "compact"
+	// OutputCompressed removes all optional whitespace and comments
+	OutputCompressed OutputStyle = "compressed"
+)
+
+// ParseOutputStyle returns the OutputStyle named s
+func ParseOutputStyle(s string) (OutputStyle, error) {
+	switch style := OutputStyle(s); style {
+	case OutputExpanded, OutputCompact, OutputCompressed:
+		return style, nil
+	case "":
+		return OutputExpanded, nil
+	}
+	return "", fmt.Errorf("unknown output style %q, expected expanded, compact or compressed", s)
+}
+
+// cssNode is a rule, at-rule, declaration or comment in rendered CSS
+type cssNode struct {
+	prelude  string // selector or at-rule, empty for comments and declarations
+	comment  string
+	decl     string
+	block    bool       // false for statements like @charset "UTF-8";
+	children []*cssNode // declarations, comments and rules in source order
+	source   string     // the CSS the node was parsed from, empty once changed
+}
+
+// formatOutput lays out expanded CSS in the style set in options,
+// removing repeated rules from @media blocks if configured
+func formatOutput(css string, options Options) string {
+	style := options.OutputStyle
+	if (style == "" || style == OutputExpanded) && !options.DedupeMedia {
+		return css
+	}
+
+	nodes := parseCSS(css)
+	if options.DedupeMedia {
+		nodes = dedupeMedia(nodes)
+	}
+
+	var buf strings.Builder
+	switch style {
+	case "", OutputExpanded:
+		// Only rules changed by deduplication are written again
+		for _, n := range nodes {
+			if n.source == "" {
+				writeExpanded(&buf, []*cssNode{n}, "")
+				continue
+			}
+			buf.WriteString(n.source)
+			buf.WriteByte('\n')
+		}
+	case OutputCompressed:
+		writeCompressed(&buf, nodes)
+		if buf.Len() > 0 {
+			buf.WriteByte('\n')
+		}
+	case OutputCompact:
+		writeCompact(&buf, nodes, "")
+	}
+	return buf.String()
+}
+
+// trimWhitespace removes trailing spaces and tabs from every line and ends
+// non-empty css with exactly one newline, or none with omitFinalNewline
+func trimWhitespace(css string, omitFinalNewline bool) string {
+	lines := strings.Split(strings.TrimRight(css, " \t\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t")
+	}
+	css = strings.Join(lines, "\n")
+	if css == "" || omitFinalNewline {
+		return css
+	}
+	return css + "\n"
+}
+
+// parseCSS splits rendered CSS into rules, declarations and comments.
+// Strings, comments and parentheses (like url(data:...;...)) are skipped
+// when looking for the braces and semicolons that delimit them.
+func parseCSS(css string) []*cssNode {
+	root := &cssNode{}
+	stack := []*cssNode{root}
+	starts := []int{0} // where the rules on the stack start
+	start := 0
+
+	text := func(end int) string {
+		return collapseSpace(css[start:end])
+	}
+
+	for i := 0; i < len(css); i++ {
+		current := stack[len(stack)-1]
+
+		switch ch := css[i]; {
+		case ch == '"' || ch == '\'':
+			for i++; i < len(css) && css[i] != ch; i++ {
+				if css[i] == '\\' {
+					i++
+				}
+			}
+
+		case ch == '(':
+			for depth := 0; i < len(css); i++ {
+				if css[i] == '(' {
+					depth++
+				} else if css[i] == ')' {
+					if depth--; depth == 0 {
+						break
+					}
+				}
+			}
+
+		case ch == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := len(css)
+			if j := strings.Index(css[i+2:], "*/"); j != -1 {
+				end = i + 2 + j + 2
+			}
+			// Comments on their own are kept, comments within a value stay in it
+			if text(i) == "" {
+				current.children = append(current.children, &cssNode{comment: css[i:end], source: css[i:end]})
+				start = end
+			}
+			i = end - 1
+
+		case ch == '{':
+			node := &cssNode{prelude: text(i), block: true}
+			current.children = append(current.children, node)
+			stack = append(stack, node)
+			starts = append(starts, start+strings.Index(css[start:i], strings.TrimSpace(css[start:i])))
+			start = i + 1
+
+		case ch == ';':
+			if decl := text(i); decl != "" {
+				if len(stack) == 1 {
+					// Statements like @charset "UTF-8";
+					current.children = append(current.children, &cssNode{prelude: decl, source: decl + ";"})
+				} else {
+					current.children = append(current.children, &cssNode{decl: decl})
+				}
+			}
+			start = i + 1
+
+		case ch == '}':
+			if decl := text(i); decl != "" {
+				current.children = append(current.children, &cssNode{decl: decl})
+			}
+			if len(stack) > 1 {
+				current.source = css[starts[len(starts)-1] : i+1]
+				stack, starts = stack[:len(stack)-1], starts[:len(starts)-1]
+			}
+			start = i + 1
+		}
+	}
+	return root.children
+}
+
+// isStatement reports whether n is an at-rule without a block, like @charset
+func (n *cssNode) isStatement() bool {
+	return n.comment == "" && n.decl == "" && !n.block
+}
+
+// hasRules reports whether n holds anything besides declarations, like the
+// rules of an @media block or comments
+func (n *cssNode) hasRules() bool {
+	for _, child := range n.children {
+		if child.decl == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeMedia merges adjacent @media blocks with the same condition and
+// removes rules repeated within them, like the same responsive rule coming
+// from two mixin calls. The last copy of a rule is kept, so the cascade
+// doesn't change.
+func dedupeMedia(nodes []*cssNode) []*cssNode {
+	result := make([]*cssNode, 0, len(nodes))
+	for _, n := range nodes {
+		if !n.isMedia() {
+			result = append(result, n)
+			continue
+		}
+		if last := len(result) - 1; last >= 0 && result[last].isMedia() && result[last].prelude == n.prelude {
+			result[last].children = append(result[last].children, n.children...)
+			result[last].source = ""
+			continue
+		}
+		result = append(result, n)
+	}
+
+	for _, n := range result {
+		if !n.isMedia() {
+			continue
+		}
+		n.children = dedupeMedia(n.children)
+
+		last := make(map[string]int, len(n.children))
+		for i, child := range n.children {
+			last[child.key()] = i
+		}
+		rules := n.children[:0]
+		for i, child := range n.children {
+			if last[child.key()] == i {
+				rules = append(rules, child)
+			} else {
+				n.source = ""
+			}
+		}
+		n.children = rules
+	}
+	return result
+}
+
+// isMedia reports whether n is an @media block holding only rules
+func (n *cssNode) isMedia() bool {
+	if !n.block || !strings.HasPrefix(n.prelude, "@media") {
+		return false
+	}
+	for _, child := range n.children {
+		if child.decl != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// key identifies a rule by its selector and contents
+func (n *cssNode) key() string {
+	switch {
+	case n.comment != "":
+		return n.comment
+	case n.decl != "":
+		return stripPositions(n.decl)
+	}
+	keys := make([]string, len(n.children))
+	for i, child := range n.children {
+		keys[i] = child.key()
+	}
+	return stripPositions(n.prelude) + "{" + strings.Join(keys, ";") + "}"
+}
+
+// writeExpanded writes each declaration on its own line, the way the
+// renderer lays out CSS
+func writeExpanded(buf *strings.Builder, nodes []*cssNode, indent string) {
+	for _, n := range nodes {
+		buf.WriteString(indent)
+		switch {
+		case n.comment != "":
+			buf.WriteString(n.comment)
+		case n.decl != "":
+			buf.WriteString(n.decl)
+			buf.WriteByte(';')
+		case n.isStatement():
+			buf.WriteString(n.prelude)
+			buf.WriteByte(';')
+		default:
+			writeSelectorList(buf, n.prelude, indent)
+			buf.WriteString(" {\n")
+			writeExpanded(buf, n.children, indent+"  ")
+			buf.WriteString(indent)
+			buf.WriteByte('}')
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// writeSelectorList writes each selector of a rule on its own line, commas
+// within parentheses and at-rule preludes are kept in place
+func writeSelectorList(buf *strings.Builder, prelude, indent string) {
+	if strings.HasPrefix(prelude, "@") {
+		buf.WriteString(prelude)
+		return
+	}
+	depth := 0
+	for i := 0; i < len(prelude); i++ {
+		ch := prelude[i]
+		switch {
+		case ch == '(' || ch == '[':
+			depth++
+		case ch == ')' || ch == ']':
+			depth--
+		case ch == ',' && depth == 0:
+			buf.WriteString(",\n")
+			buf.WriteString(indent)
+			for i+1 < len(prelude) && prelude[i+1] == ' ' {
+				i++
+			}
+			continue
+		}
+		buf.WriteByte(ch)
+	}
+}
+
+// writeCompact writes one rule per line; blocks holding rules, like
+// @media, open and close on their own lines with the rules indented and
+// consecutive declarations on one line
+func writeCompact(buf *strings.Builder, nodes []*cssNode, indent string) {
+	for i := 0; i < len(nodes); i++ {
+		n := nodes[i]
+		buf.WriteString(indent)
+		switch {
+		case n.comment != "":
+			buf.WriteString(n.comment)
+		case n.decl != "":
+			buf.WriteString(n.decl)
+			for i+1 < len(nodes) && nodes[i+1].decl != "" {
+				i++
+				buf.WriteString("; ")
+				buf.WriteString(nodes[i].decl)
+			}
+			buf.WriteByte(';')
+		case n.isStatement():
+			buf.WriteString(n.prelude)
+			buf.WriteByte(';')
+		case n.hasRules():
+			buf.WriteString(n.prelude)
+			buf.WriteString(" {\n")
+			writeCompact(buf, n.children, indent+"  ")
+			buf.WriteString(indent)
+			buf.WriteByte('}')
+		default:
+			buf.WriteString(n.prelude)
+			buf.WriteString(" { ")
+			for _, decl := range n.children {
+				buf.WriteString(decl.decl)
+				buf.WriteString("; ")
+			}
+			buf.WriteByte('}')
+		}
+		buf.WriteByte('\n')
+	}
+}
+
+// writeCompressed writes nodes without optional whitespace. Comments are
+// dropped except for /*! ... */ comments, which are meant to be preserved.
+func writeCompressed(buf *strings.Builder, nodes []*cssNode) {
+	for i, n := range nodes {
+		switch {
+		case n.comment != "":
+			if strings.HasPrefix(n.comment, "/*!") {
+				buf.WriteString(n.comment)
+			}
+		case n.decl != "":
+			buf.WriteString(compressDecl(n.decl))
+			if i < len(nodes)-1 {
+				buf.WriteByte(';')
+			}
+		case n.isStatement():
+			buf.WriteString(n.prelude)
+			buf.WriteByte(';')
+		default:
+			buf.WriteString(compressCommas(n.prelude))
+			buf.WriteByte('{')
+			writeCompressed(buf, n.children)
+			buf.WriteByte('}')
+		}
+	}
+}
+
+// compressDecl removes the space after the property colon and after commas
+func compressDecl(decl string) string {
+	prop, value, ok := strings.Cut(decl, ":")
+	if !ok {
+		return decl
+	}
+	return strings.TrimSpace(prop) + ":" + compressCommas(strings.TrimSpace(value))
+}
+
+// collapseSpace trims s and turns each run of whitespace outside quoted
+// strings into a single space
+func collapseSpace(s string) string {
+	s = strings.TrimSpace(s)
+	var buf strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && i+1 < len(s) {
+				buf.WriteByte(ch)
+				i++
+				ch = s[i]
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == '\f':
+			for i+1 < len(s) && strings.IndexByte(" \t\n\r\f", s[i+1]) != -1 {
+				i++
+			}
+			ch = ' '
+		}
+		buf.WriteByte(ch)
+	}
+	return buf.String()
+}
+
+// compressCommas removes the spaces after commas outside quoted strings
+func compressCommas(s string) string {
+	if !strings.Contains(s, ", ") {
+		return s
+	}
+	var buf strings.Builder
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		buf.WriteByte(ch)
+		switch {
+		case quote != 0:
+			if ch == '\\' && i+1 < len(s) {
+				i++
+				buf.WriteByte(s[i])
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == ',':
+			for i+1 < len(s) && s[i+1] == ' ' {
+				i++
+			}
+		}
+	}
+	return buf.String()
+}
@@ -1,6 +1,9 @@
 package renderer
 
-import "github.com/titpetric/lessgo/internal/strings"
+import (
+	"github.com/titpetric/lessgo/dst"
+	"github.com/titpetric/lessgo/internal/strings"
+)
 
 // isValueChar checks if a character can be part of a value
 func isValueChar(r rune) bool {
@@ -17,35 +20,141 @@ func isVarChar(r rune) bool {
 	return (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' || r == '-'
 }
 
-// normalizeSelectorSpacing normalizes spaces around CSS combinators (+, >, ~)
-// Ensures consistent spacing: " + " regardless of input spacing
-func normalizeSelectorSpacing(sel string) string {
-	// First, remove all spaces around combinators
-	sel = strings.ReplaceAll(sel, " + ", "+")
-	sel = strings.ReplaceAll(sel, " > ", ">")
-	sel = strings.ReplaceAll(sel, " ~ ", "~")
+// selector will combine a parent and child selector. Every & in the child
+// is replaced with the parent, e.g. "& + &" becomes ".a + .a". Spacing
+// around combinators is normalized with dst.FormatSelector.
+func selector(parent, child string) string {
+	child = dst.FormatSelector(child)
+	if parent == "" {
+		return child
+	}
+	if strings.Contains(child, "&") {
+		return dst.FormatSelector(strings.ReplaceAll(child, "&", parent))
+	}
+	return parent + " " + child
+}
 
-	// Then add consistent spacing around combinators
-	sel = strings.ReplaceAll(sel, "+", " + ")
-	sel = strings.ReplaceAll(sel, ">", " > ")
-	sel = strings.ReplaceAll(sel, "~", " ~ ")
+// cutImportant returns value without a trailing !important flag and
+// reports whether it was there, e.g. "10px !important" gives "10px"
+func cutImportant(value string) (string, bool) {
+	rest, ok := strings.CutSuffix(strings.TrimSpace(value), "!important")
+	rest = strings.TrimSpace(rest)
+	if !ok || rest == "" {
+		return value, false
+	}
+	return rest, true
+}
 
-	// Clean up any multiple spaces that may have been created
-	for strings.Contains(sel, "  ") {
-		sel = strings.ReplaceAll(sel, "  ", " ")
+// undefinedVariable returns the name of the first variable value refers to
+// that isn't defined in stack. Quoted strings, including escaped values like
+// ~"@x", and unquoted url() arguments like url(img@2x.png) are skipped.
+func undefinedVariable(stack *Stack, value string) (string, bool) {
+	var quote byte
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		switch {
+		case quote == 0 && (ch == 'u' || ch == 'U') && strings.EqualFold(value[i:min(i+4, len(value))], "url(") && (i == 0 || !isVarChar(rune(value[i-1]))):
+			if end := strings.IndexByte(value[i:], ')'); end != -1 && strings.IndexAny(value[i+4:i+end], "\"'") == -1 {
+				i += end
+			}
+		case quote != 0:
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '@':
+			end := i + 1
+			for end < len(value) && isVarChar(rune(value[end])) {
+				end++
+			}
+			if end == i+1 {
+				continue
+			}
+			if _, ok := stack.Get(value[i+1 : end]); !ok {
+				return value[i+1 : end], true
+			}
+			i = end - 1
+		}
 	}
+	return "", false
+}
+
+// zeroUnits are the length and percentage units a zero value can drop
+var zeroUnits = []string{"px", "em", "rem", "ex", "ch", "vw", "vh", "vmin", "vmax", "cm", "mm", "in", "pt", "pc", "q", "%"}
 
-	return sel
+// zeroUnitProperties need the unit on a zero value, a bare 0 is invalid or
+// read differently, e.g. as a flex-grow factor in the flex shorthand
+var zeroUnitProperties = map[string]bool{
+	"flex":       true,
+	"flex-basis": true,
 }
 
-// selector will combine a parent and child selector.
-func selector(parent, child string) string {
-	child = normalizeSelectorSpacing(child)
-	if parent == "" {
-		return child
+// stripZeroUnits replaces zero lengths and percentages in a value of
+// property with a bare 0. Values in functions like hsl() or url() and
+// quoted strings are left alone.
+func stripZeroUnits(property, value string) string {
+	if strings.HasPrefix(property, "--") || zeroUnitProperties[strings.ToLower(property)] {
+		return value
+	}
+
+	var buf strings.Builder
+	var quote byte
+	depth, start := 0, 0
+	flush := func(end int) {
+		token := value[start:end]
+		if depth == 0 && isZeroLength(token) {
+			token = "0"
+		}
+		buf.WriteString(token)
 	}
-	if strings.HasPrefix(child, "&") {
-		return normalizeSelectorSpacing(parent + child[1:])
+	for i := 0; i < len(value); i++ {
+		ch := value[i]
+		if quote != 0 {
+			if ch == '\\' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch ch {
+		case '"', '\'':
+			quote = ch
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ' ', ',', '/':
+			flush(i)
+			buf.WriteByte(ch)
+			start = i + 1
+		}
 	}
-	return parent + " " + child
+	flush(len(value))
+	return buf.String()
+}
+
+// isZeroLength checks if token is a zero with a length or percentage unit, e.g. 0px or 0.0%
+func isZeroLength(token string) bool {
+	number := token
+	if number != "" && (number[0] == '-' || number[0] == '+') {
+		number = number[1:]
+	}
+	end := 0
+	for end < len(number) && (number[end] == '0' || number[end] == '.') {
+		end++
+	}
+	if end == 0 || number[:end] == "." {
+		return false
+	}
+	unit := strings.ToLower(number[end:])
+	for _, u := range zeroUnits {
+		if unit == u {
+			return true
+		}
+	}
+	return false
 }